@@ -0,0 +1,71 @@
+package main
+
+import "openvpn-status-parser/parser"
+
+// annotations add data from files other than the status file, such as the
+// address pool or the certificate inventory, to clients and routes.
+type annotations struct {
+	// clients are called for every client
+	clients []func(*parser.Client)
+
+	// routes are called for every routing table entry
+	routes []func(*parser.Route)
+
+	// done are called once all clients and routes have been annotated
+	done []func()
+}
+
+// empty reports whether there is nothing to annotate
+func (a *annotations) empty() bool {
+	return len(a.clients) == 0 && len(a.routes) == 0 && len(a.done) == 0
+}
+
+// handler returns a Handler annotating clients and routes before passing
+// them to h, h itself if there is nothing to annotate.
+func (a *annotations) handler(h parser.Handler) parser.Handler {
+	if a.empty() {
+		return h
+	}
+	return parser.HandlerFuncs{
+		Client: func(client parser.Client) error {
+			a.annotateClient(&client)
+			return h.HandleClient(client)
+		},
+		Route: func(route parser.Route) error {
+			a.annotateRoute(&route)
+			return h.HandleRoute(route)
+		},
+	}
+}
+
+// apply annotates the clients and routes of a parsed status and finishes.
+func (a *annotations) apply(status *parser.Status) {
+	for i := range status.ClientList {
+		a.annotateClient(&status.ClientList[i])
+	}
+	for i := range status.RoutingTable {
+		a.annotateRoute(&status.RoutingTable[i])
+	}
+	a.finish()
+}
+
+// finish calls the done functions
+func (a *annotations) finish() {
+	for _, done := range a.done {
+		done()
+	}
+}
+
+// annotateClient calls the client functions
+func (a *annotations) annotateClient(client *parser.Client) {
+	for _, annotate := range a.clients {
+		annotate(client)
+	}
+}
+
+// annotateRoute calls the route functions
+func (a *annotations) annotateRoute(route *parser.Route) {
+	for _, annotate := range a.routes {
+		annotate(route)
+	}
+}
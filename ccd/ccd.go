@@ -0,0 +1,313 @@
+// Package ccd reads the per-client config files of the client-config-dir
+// directive and checks the static addresses and iroutes configured there
+// against the connected clients and the routing table.
+package ccd
+
+import (
+	"fmt"
+	"net/netip"
+	"openvpn-status-parser/config"
+	"openvpn-status-parser/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultFile is used by OpenVPN for clients without a file of their own
+const defaultFile = "DEFAULT"
+
+// Config is the part of a client config file this package knows about.
+type Config struct {
+	// CommonName is the file name, the client's common name
+	CommonName string
+
+	// StaticAddress is the client address from ifconfig-push, invalid if none
+	StaticAddress netip.Addr
+
+	// StaticIPv6Address is the client address from ifconfig-ipv6-push,
+	// invalid if none
+	StaticIPv6Address netip.Addr
+
+	// IRoutes are the iroute and iroute-ipv6 networks
+	IRoutes []netip.Prefix
+}
+
+// Dir is a client-config-dir.
+type Dir struct {
+	byCommonName map[string]*Config
+}
+
+// ReadDir reads all files in dir. Invalid directives are skipped and
+// returned as errors; the Dir is nil only if dir could not be read.
+func ReadDir(dir string) (*Dir, []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read client-config-dir: %w", err)}
+	}
+
+	d := &Dir{byCommonName: make(map[string]*Config)}
+	var errs []error
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		cfg, fileErrors := readFile(path, entry.Name())
+		errs = append(errs, fileErrors...)
+		if cfg != nil {
+			d.byCommonName[cfg.CommonName] = cfg
+		}
+	}
+	return d, errs
+}
+
+// Len returns the number of client config files
+func (d *Dir) Len() int {
+	return len(d.byCommonName)
+}
+
+// Lookup returns the config of the client with common name cn, the
+// DEFAULT file if it has none, as OpenVPN does.
+func (d *Dir) Lookup(cn string) (*Config, bool) {
+	if cfg, ok := d.byCommonName[cn]; ok {
+		return cfg, true
+	}
+	cfg, ok := d.byCommonName[defaultFile]
+	return cfg, ok
+}
+
+// readFile reads a client config file. Its lines are split by
+// config.ReadDirectives, as OpenVPN reads it like the server config.
+// Errors of single directives are prefixed with path.
+func readFile(path, cn string) (*Config, []error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer file.Close()
+
+	directives, err := config.ReadDirectives(file, path)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	cfg := &Config{CommonName: cn}
+	var parseErrors []error
+	for _, d := range directives {
+		if err := cfg.parseDirective(d); err != nil {
+			parseErrors = append(parseErrors, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	return cfg, parseErrors
+}
+
+// parseDirective handles a directive of a client config file.
+// Directives not related to addresses are ignored.
+//
+//	ifconfig-push <local> <remote-netmask>
+//	ifconfig-ipv6-push <address/bits> [remote]
+//	iroute <network> [netmask]
+//	iroute-ipv6 <network/bits>
+func (cfg *Config) parseDirective(d config.Directive) error {
+	switch d.Name {
+	case "ifconfig-push", "ifconfig-ipv6-push", "iroute", "iroute-ipv6":
+	default:
+		return nil
+	}
+
+	if len(d.Args) < 1 {
+		return parser.ParseError{
+			Line: d.Line, Field: d.Name, Kind: parser.KindShortRecord,
+			Err: fmt.Errorf("missing address"),
+		}
+	}
+	value := d.Args[0]
+	badValue := func(err error) error {
+		return parser.ParseError{Line: d.Line, Field: d.Name, Value: value, Kind: parser.KindBadValue, Err: err}
+	}
+
+	switch d.Name {
+	case "ifconfig-push":
+		addr, err := netip.ParseAddr(value)
+		if err != nil || !addr.Is4() {
+			return badValue(fmt.Errorf("invalid IPv4 address"))
+		}
+		cfg.StaticAddress = addr
+
+	case "ifconfig-ipv6-push":
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil || !prefix.Addr().Is6() {
+			return badValue(fmt.Errorf("invalid IPv6 address, expected address/bits"))
+		}
+		cfg.StaticIPv6Address = prefix.Addr()
+
+	case "iroute":
+		addr, err := netip.ParseAddr(value)
+		if err != nil || !addr.Is4() {
+			return badValue(fmt.Errorf("invalid IPv4 network"))
+		}
+		bits := 32
+		if len(d.Args) > 1 {
+			mask, err := netip.ParseAddr(d.Args[1])
+			if bits, err = maskBits(mask, err); err != nil {
+				return parser.ParseError{Line: d.Line, Field: d.Name, Value: d.Args[1], Kind: parser.KindBadValue, Err: err}
+			}
+		}
+		cfg.IRoutes = append(cfg.IRoutes, netip.PrefixFrom(addr, bits).Masked())
+
+	case "iroute-ipv6":
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil || !prefix.Addr().Is6() {
+			return badValue(fmt.Errorf("invalid IPv6 network, expected network/bits"))
+		}
+		cfg.IRoutes = append(cfg.IRoutes, prefix.Masked())
+	}
+	return nil
+}
+
+// maskBits returns the prefix length of a dotted netmask such as 255.255.255.0
+func maskBits(mask netip.Addr, err error) (int, error) {
+	if err != nil || !mask.Is4() {
+		return 0, fmt.Errorf("invalid netmask")
+	}
+	b := mask.As4()
+	n := uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+	bits := 0
+	for n&(1<<31) != 0 {
+		bits++
+		n <<= 1
+	}
+	if n != 0 {
+		return 0, fmt.Errorf("invalid netmask")
+	}
+	return bits, nil
+}
+
+// Checker annotates clients and routes from a client-config-dir as they
+// are parsed and collects the iroutes of connected clients that do not
+// appear in the routing table.
+type Checker struct {
+	dir  *Dir
+	info *parser.CCDInfo
+
+	// pending are the iroutes of connected clients not yet seen in the
+	// routing table, by common name
+	pending map[string]map[netip.Prefix]bool
+
+	// order are the common names of pending in the order clients were seen
+	order []string
+}
+
+// NewChecker creates a checker for dir.
+func NewChecker(dir *Dir) *Checker {
+	return &Checker{
+		dir:     dir,
+		info:    &parser.CCDInfo{Files: dir.Len()},
+		pending: make(map[string]map[netip.Prefix]bool),
+	}
+}
+
+// Info returns the summary the checker fills in; MissingIRoutes is set by
+// Finish.
+func (c *Checker) Info() *parser.CCDInfo {
+	return c.info
+}
+
+// AnnotateClient sets the static addresses, StaticMismatch and IRoutes of
+// client from its config file. Clients without config are left unchanged.
+func (c *Checker) AnnotateClient(client *parser.Client) {
+	cfg, ok := c.dir.Lookup(client.CommonName)
+	if !ok {
+		return
+	}
+
+	if cfg.StaticAddress.IsValid() {
+		client.StaticAddress = cfg.StaticAddress.String()
+		if differs(client.VirtualAddress, cfg.StaticAddress) {
+			client.StaticMismatch = true
+		}
+	}
+	if cfg.StaticIPv6Address.IsValid() {
+		client.StaticIPv6Address = cfg.StaticIPv6Address.String()
+		if differs(client.VirtualIPv6Address, cfg.StaticIPv6Address) {
+			client.StaticMismatch = true
+		}
+	}
+
+	if len(cfg.IRoutes) == 0 {
+		return
+	}
+	client.IRoutes = make([]string, 0, len(cfg.IRoutes))
+	if _, seen := c.pending[client.CommonName]; !seen {
+		c.pending[client.CommonName] = make(map[netip.Prefix]bool)
+		c.order = append(c.order, client.CommonName)
+	}
+	for _, prefix := range cfg.IRoutes {
+		client.IRoutes = append(client.IRoutes, prefix.String())
+		c.pending[client.CommonName][prefix] = true
+	}
+}
+
+// AnnotateRoute sets CCDDirective of route and marks iroutes as seen.
+func (c *Checker) AnnotateRoute(route *parser.Route) {
+	prefix, ok := parseRoute(route.VirtualAddress)
+	if !ok {
+		return
+	}
+
+	cfg, ok := c.dir.Lookup(route.CommonName)
+	if !ok {
+		return
+	}
+
+	for _, iroute := range cfg.IRoutes {
+		if iroute == prefix {
+			route.CCDDirective = "iroute"
+			delete(c.pending[route.CommonName], prefix)
+			return
+		}
+	}
+	if prefix.IsSingleIP() && (prefix.Addr() == cfg.StaticAddress || prefix.Addr() == cfg.StaticIPv6Address) {
+		route.CCDDirective = "ifconfig-push"
+	}
+}
+
+// Finish records the iroutes that were not seen in the routing table.
+// It is called once all clients and routes have been annotated.
+func (c *Checker) Finish() {
+	for _, cn := range c.order {
+		// Clients are only pending if they have a config
+		cfg, _ := c.dir.Lookup(cn)
+		for _, prefix := range cfg.IRoutes {
+			if c.pending[cn][prefix] {
+				c.info.MissingIRoutes = append(c.info.MissingIRoutes, parser.MissingIRoute{
+					CommonName: cn,
+					Network:    prefix.String(),
+				})
+			}
+		}
+	}
+}
+
+// parseRoute parses a routing table address: a network in CIDR notation,
+// or a single address (OpenVPN omits /32 and /128). Learned addresses of
+// tap servers end in "C" and are not matched.
+func parseRoute(s string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), true
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
+
+// differs reports whether the current address is known and not expected
+func differs(current string, expected netip.Addr) bool {
+	if current == "" {
+		return false
+	}
+	addr, err := netip.ParseAddr(current)
+	return err != nil || addr != expected
+}
@@ -0,0 +1,162 @@
+package ccd
+
+import (
+	"openvpn-status-parser/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestDir creates a client-config-dir with the given files
+func writeTestDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestReadDir tests reading static addresses and iroutes
+func TestReadDir(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"site-a": `# Branch office A
+ifconfig-push "10.8.0.10" 255.255.255.0 # office router
+ifconfig-ipv6-push fd00::10/64
+--iroute 192.168.10.0 255.255.255.0
+iroute 192.168.11.5
+iroute-ipv6 fd10::/48
+push "route 192.168.0.0 255.255.0.0"
+<tls-crypt-v2>
+iroute 192.168.99.0 255.255.255.0
+</tls-crypt-v2>
+`,
+		"DEFAULT": "push-reset\n",
+	})
+
+	d, errs := ReadDir(dir)
+	if len(errs) > 0 {
+		t.Fatalf("ReadDir failed: %v", errs)
+	}
+	if d.Len() != 2 {
+		t.Errorf("Expected 2 files, got %d", d.Len())
+	}
+
+	cfg, ok := d.Lookup("site-a")
+	if !ok {
+		t.Fatal("Expected config for site-a")
+	}
+	if cfg.StaticAddress.String() != "10.8.0.10" || cfg.StaticIPv6Address.String() != "fd00::10" {
+		t.Errorf("Expected static addresses 10.8.0.10 and fd00::10, got %v and %v", cfg.StaticAddress, cfg.StaticIPv6Address)
+	}
+
+	var iroutes []string
+	for _, prefix := range cfg.IRoutes {
+		iroutes = append(iroutes, prefix.String())
+	}
+	if strings.Join(iroutes, " ") != "192.168.10.0/24 192.168.11.5/32 fd10::/48" {
+		t.Errorf("Expected iroutes 192.168.10.0/24 192.168.11.5/32 fd10::/48, got %v", iroutes)
+	}
+
+	// Clients without file of their own get DEFAULT
+	if cfg, ok := d.Lookup("laptop"); !ok || cfg.CommonName != "DEFAULT" {
+		t.Errorf("Expected DEFAULT config for laptop, got %+v", cfg)
+	}
+}
+
+// TestReadDirErrors tests that invalid directives are reported and skipped
+func TestReadDirErrors(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"site-b": `ifconfig-push
+ifconfig-push 10.8.0.300 255.255.255.0
+iroute 192.168.20.0 255.0.255.0
+iroute-ipv6 192.168.21.0/24
+iroute 192.168.22.0 255.255.255.0
+`,
+	})
+
+	d, errs := ReadDir(dir)
+	if len(errs) != 4 {
+		t.Fatalf("Expected 4 errors, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "site-b") {
+		t.Errorf("Expected error to name the file, got %v", errs[0])
+	}
+
+	cfg, _ := d.Lookup("site-b")
+	if cfg.StaticAddress.IsValid() || len(cfg.IRoutes) != 1 {
+		t.Errorf("Expected only the valid iroute to be read, got %+v", cfg)
+	}
+
+	// The file is split like a server config, an unclosed quote is an error
+	dir = writeTestDir(t, map[string]string{"site-c": "ifconfig-push \"10.8.0.30 255.255.255.0\n"})
+	if _, errs := ReadDir(dir); len(errs) != 1 || !strings.Contains(errs[0].Error(), "site-c:1") {
+		t.Errorf("Expected quoting error at site-c:1, got %v", errs)
+	}
+
+	if _, errs := ReadDir(filepath.Join(dir, "missing")); len(errs) != 1 {
+		t.Errorf("Expected one error for missing directory, got %v", errs)
+	}
+}
+
+// TestChecker tests annotating clients and routes and finding missing iroutes
+func TestChecker(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"site-a": "ifconfig-push 10.8.0.10 255.255.255.0\niroute 192.168.10.0 255.255.255.0\niroute 192.168.11.0 255.255.255.0\n",
+		"site-b": "ifconfig-push 10.8.0.20 255.255.255.0\n",
+		"site-c": "iroute 192.168.30.0 255.255.255.0\n",
+	})
+	d, _ := ReadDir(dir)
+	c := NewChecker(d)
+
+	clients := []parser.Client{
+		{CommonName: "site-a", VirtualAddress: "10.8.0.10"},
+		{CommonName: "site-b", VirtualAddress: "10.8.0.21"},
+		{CommonName: "laptop", VirtualAddress: "10.8.0.30"},
+	}
+	for i := range clients {
+		c.AnnotateClient(&clients[i])
+	}
+
+	if clients[0].StaticAddress != "10.8.0.10" || clients[0].StaticMismatch {
+		t.Errorf("Expected site-a on its static address, got %+v", clients[0])
+	}
+	if strings.Join(clients[0].IRoutes, " ") != "192.168.10.0/24 192.168.11.0/24" {
+		t.Errorf("Expected site-a iroutes, got %v", clients[0].IRoutes)
+	}
+	if clients[1].StaticAddress != "10.8.0.20" || !clients[1].StaticMismatch {
+		t.Errorf("Expected site-b to be flagged, got %+v", clients[1])
+	}
+	if clients[2].StaticAddress != "" || clients[2].StaticMismatch {
+		t.Errorf("Expected laptop to be left unchanged, got %+v", clients[2])
+	}
+
+	routes := []parser.Route{
+		{VirtualAddress: "10.8.0.10", CommonName: "site-a"},
+		{VirtualAddress: "192.168.10.0/24", CommonName: "site-a"},
+		{VirtualAddress: "10.8.0.30", CommonName: "laptop"},
+	}
+	for i := range routes {
+		c.AnnotateRoute(&routes[i])
+	}
+	c.Finish()
+
+	for i, expected := range []string{"ifconfig-push", "iroute", ""} {
+		if routes[i].CCDDirective != expected {
+			t.Errorf("Route %s: expected CCDDirective '%s', got '%s'", routes[i].VirtualAddress, expected, routes[i].CCDDirective)
+		}
+	}
+
+	// site-c is not connected, its iroute is not expected in the routing table
+	info := c.Info()
+	if info.Files != 3 {
+		t.Errorf("Expected 3 files, got %d", info.Files)
+	}
+	if len(info.MissingIRoutes) != 1 || info.MissingIRoutes[0] != (parser.MissingIRoute{CommonName: "site-a", Network: "192.168.11.0/24"}) {
+		t.Errorf("Expected 192.168.11.0/24 of site-a to be missing, got %+v", info.MissingIRoutes)
+	}
+}
@@ -1,7 +1,6 @@
 package config
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -31,6 +30,30 @@ type ServerConfig struct {
 	
 	// StatusVersion is the status file format version (1, 2, or 3)
 	StatusVersion int `json:"-"`
+
+	// StatusVersionSet is true if StatusVersion comes from a
+	// status-version directive rather than the default
+	StatusVersionSet bool `json:"-"`
+
+	// LogFile is the path of the log file from the log or log-append
+	// directive, empty if OpenVPN logs to syslog
+	LogFile string `json:"-"`
+
+	// PoolFile is the path of the file from the ifconfig-pool-persist
+	// directive, empty if addresses are not persisted
+	PoolFile string `json:"-"`
+
+	// CRLFile is the path of the CRL file, or directory with the "dir"
+	// flag, from the crl-verify directive, empty if none
+	CRLFile string `json:"-"`
+
+	// ClientConfigDir is the directory from the client-config-dir
+	// directive, empty if none
+	ClientConfigDir string `json:"-"`
+
+	// Directives are all directives read, including those of included
+	// files, in the order OpenVPN reads them
+	Directives []Directive `json:"-"`
 }
 
 // ParseConfig reads an OpenVPN server configuration file and extracts
@@ -43,90 +66,193 @@ type ServerConfig struct {
 // - dev <device>              # tun or tap
 // - status <file> [seconds]   # Status file path (we use only the path)
 // - status-version <n>        # Status file version: 1, 2, or 3
+// - log <file>                # Log file path
+// - log-append <file>         # Log file path
+// - ifconfig-pool-persist <file> [seconds]  # Persisted pool addresses
+// - crl-verify <file> [dir]   # Certificate revocation list
+// - client-config-dir <dir>   # Per-client config files
+// - config <file>             # Included config file, read in place
+// - cd <dir>                  # Working directory of OpenVPN
+//
+// Lines are split into directives by ReadDirectives, so quoted
+// parameters, the --option form and inline files such as <ca> are
+// handled as OpenVPN does.
+//
+// Relative paths are resolved as OpenVPN does from its working directory:
+// cd changes the directory at once, so included files are opened relative
+// to the directory at that point, while the other files are opened by
+// OpenVPN after all options were read and are resolved against the final
+// directory. The working directory OpenVPN was started in is not known
+// from the config; ParseConfig assumes the directory of configPath, which
+// is what the systemd units of OpenVPN set up. ParseConfigWithOptions
+// takes another one.
 func ParseConfig(configPath string) (*ServerConfig, error) {
-	file, err := os.Open(configPath)
+	return ParseConfigWithOptions(configPath, ParseOptions{})
+}
+
+// ParseOptions controls how ParseConfigWithOptions reads a config file.
+type ParseOptions struct {
+	// WorkingDir is the directory OpenVPN is started in, which relative
+	// paths are resolved against until a cd directive. The directory of
+	// the config file if empty.
+	WorkingDir string
+}
+
+// ParseConfigWithOptions reads an OpenVPN server configuration file like
+// ParseConfig, with the working directory of OpenVPN from opts.
+func ParseConfigWithOptions(configPath string, opts ParseOptions) (*ServerConfig, error) {
+	path, err := filepath.Abs(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open config file: %w", err)
 	}
-	defer file.Close()
 
-	config := &ServerConfig{
-		Port:          "1194", // Default port
-		StatusVersion: 3,      // Default to v3 if not specified
+	dir := filepath.Dir(path)
+	if opts.WorkingDir != "" {
+		if dir, err = filepath.Abs(opts.WorkingDir); err != nil {
+			return nil, fmt.Errorf("invalid working directory: %w", err)
+		}
+	}
+
+	p := &configParser{
+		config: &ServerConfig{
+			Port:          "1194", // Default port
+			StatusVersion: 3,      // Default to v3 if not specified
+		},
+		dir: dir,
+	}
+	if err := p.readFile(path); err != nil {
+		return nil, err
+	}
+	p.resolvePaths()
+
+	// Validate that we found a status file
+	if p.config.StatusFile == "" {
+		return nil, fmt.Errorf("no 'status' directive found in config file")
 	}
 
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
+	return p.config, nil
+}
 
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
+// configParser holds the state of ParseConfig across included files
+type configParser struct {
+	config *ServerConfig
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
-			continue
+	// dir is the effective working directory, changed by cd
+	dir string
+
+	// including are the files being read, outermost first, to detect
+	// include cycles
+	including []string
+}
+
+// readFile reads the config file at the absolute path and the files it
+// includes.
+func (p *configParser) readFile(path string) error {
+	for i, including := range p.including {
+		if including == path {
+			cycle := append(append([]string{}, p.including[i:]...), path)
+			return fmt.Errorf("config include cycle: %s", strings.Join(cycle, " -> "))
 		}
+	}
+	p.including = append(p.including, path)
+	defer func() { p.including = p.including[:len(p.including)-1] }()
 
-		// Split line into tokens (space or tab separated)
-		tokens := strings.Fields(line)
-		if len(tokens) == 0 {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	directives, err := ReadDirectives(file, path)
+	if err != nil {
+		return err
+	}
+
+	config := p.config
+	for _, d := range directives {
+		config.Directives = append(config.Directives, d)
+
+		// Inline files such as <ca> are not file paths
+		if d.Inline || len(d.Args) == 0 {
 			continue
 		}
+		arg := d.Args[0]
 
-		directive := tokens[0]
-
-		switch directive {
+		switch d.Name {
 		case "local":
-			if len(tokens) >= 2 {
-				config.Local = tokens[1]
-			}
+			config.Local = arg
 
 		case "port":
-			if len(tokens) >= 2 {
-				config.Port = tokens[1]
-			}
+			config.Port = arg
 
 		case "proto":
-			if len(tokens) >= 2 {
-				config.Proto = tokens[1]
-			}
+			config.Proto = arg
 
 		case "dev":
-			if len(tokens) >= 2 {
-				config.Dev = tokens[1]
-			}
+			config.Dev = arg
 
 		case "status":
-			if len(tokens) >= 2 {
-				// Extract only the first argument (file path)
-				// Ignore second argument (refresh interval) if present
-				config.StatusFile = tokens[1]
-				
-				// Generate server ID from status file basename
-				config.ID = getServerID(config.StatusFile)
-			}
+			// Extract only the first argument (file path)
+			// Ignore second argument (refresh interval) if present
+			config.StatusFile = arg
+
+			// Generate server ID from status file basename
+			config.ID = getServerID(config.StatusFile)
+
+		case "log", "log-append":
+			config.LogFile = arg
+
+		case "ifconfig-pool-persist":
+			config.PoolFile = arg
+
+		case "crl-verify":
+			config.CRLFile = arg
+
+		case "client-config-dir":
+			config.ClientConfigDir = arg
 
 		case "status-version":
-			if len(tokens) >= 2 {
-				if ver, err := strconv.Atoi(tokens[1]); err == nil {
-					if ver >= 1 && ver <= 3 {
-						config.StatusVersion = ver
-					}
+			if ver, err := strconv.Atoi(arg); err == nil {
+				if ver >= 1 && ver <= 3 {
+					config.StatusVersion = ver
+					config.StatusVersionSet = true
 				}
 			}
+
+		case "cd":
+			p.dir = p.resolve(arg)
+
+		case "config":
+			if err := p.readFile(p.resolve(arg)); err != nil {
+				return fmt.Errorf("%s: %w", d, err)
+			}
 		}
 	}
+	return nil
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading config file: %w", err)
+// resolve returns name relative to the effective working directory
+func (p *configParser) resolve(name string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
 	}
+	return filepath.Join(p.dir, name)
+}
 
-	// Validate that we found a status file
-	if config.StatusFile == "" {
-		return nil, fmt.Errorf("no 'status' directive found in config file")
+// resolvePaths resolves the file directives against the final working
+// directory.
+func (p *configParser) resolvePaths() {
+	for _, name := range []*string{
+		&p.config.StatusFile,
+		&p.config.LogFile,
+		&p.config.PoolFile,
+		&p.config.CRLFile,
+		&p.config.ClientConfigDir,
+	} {
+		if *name != "" {
+			*name = p.resolve(*name)
+		}
 	}
-
-	return config, nil
 }
 
 // getServerID extracts a server identifier from the status file path.
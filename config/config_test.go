@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -74,6 +76,274 @@ func TestParseConfigMinimal(t *testing.T) {
 	}
 }
 
+// TestParseConfigLogFile tests the log and log-append directives
+func TestParseConfigLogFile(t *testing.T) {
+	for _, directive := range []string{"log", "log-append"} {
+		content := "status /var/log/openvpn/status.log\n" + directive + " /var/log/openvpn/server.log\n"
+
+		tmpfile := createTempFile(t, "server-log-*.conf", content)
+		defer os.Remove(tmpfile)
+
+		config, err := ParseConfig(tmpfile)
+		if err != nil {
+			t.Fatalf("ParseConfig failed: %v", err)
+		}
+		if config.LogFile != "/var/log/openvpn/server.log" {
+			t.Errorf("Expected LogFile '/var/log/openvpn/server.log' from %s, got '%s'", directive, config.LogFile)
+		}
+	}
+}
+
+// TestParseConfigPoolFile tests the ifconfig-pool-persist directive
+func TestParseConfigPoolFile(t *testing.T) {
+	content := "status /var/log/openvpn/status.log\nifconfig-pool-persist /var/lib/openvpn/ipp.txt 600\n"
+
+	tmpfile := createTempFile(t, "server-ipp-*.conf", content)
+	defer os.Remove(tmpfile)
+
+	config, err := ParseConfig(tmpfile)
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if config.PoolFile != "/var/lib/openvpn/ipp.txt" {
+		t.Errorf("Expected PoolFile '/var/lib/openvpn/ipp.txt', got '%s'", config.PoolFile)
+	}
+}
+
+// TestParseConfigCRLFile tests the crl-verify directive
+func TestParseConfigCRLFile(t *testing.T) {
+	content := "status /var/log/openvpn/status.log\ncrl-verify /etc/openvpn/crl.pem\n"
+
+	tmpfile := createTempFile(t, "server-crl-*.conf", content)
+	defer os.Remove(tmpfile)
+
+	config, err := ParseConfig(tmpfile)
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if config.CRLFile != "/etc/openvpn/crl.pem" {
+		t.Errorf("Expected CRLFile '/etc/openvpn/crl.pem', got '%s'", config.CRLFile)
+	}
+}
+
+// TestParseConfigClientConfigDir tests the client-config-dir directive
+func TestParseConfigClientConfigDir(t *testing.T) {
+	content := "status /var/log/openvpn/status.log\nclient-config-dir /etc/openvpn/ccd\n"
+
+	tmpfile := createTempFile(t, "server-ccd-*.conf", content)
+	defer os.Remove(tmpfile)
+
+	config, err := ParseConfig(tmpfile)
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if config.ClientConfigDir != "/etc/openvpn/ccd" {
+		t.Errorf("Expected ClientConfigDir '/etc/openvpn/ccd', got '%s'", config.ClientConfigDir)
+	}
+}
+
+// TestParseConfigInclude tests config includes and cd
+func TestParseConfigInclude(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"server.conf":        "cd server\nconfig common.conf\nport 1195\n",
+		"server/common.conf": "port 1194\nstatus openvpn-status.log\nconfig ../logging.conf\nclient-config-dir ccd\n",
+		"logging.conf":       "log-append /var/log/openvpn/server.log\n",
+	})
+
+	config, err := ParseConfig(filepath.Join(dir, "server.conf"))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	// Directives after the include override it
+	if config.Port != "1195" {
+		t.Errorf("Expected Port '1195', got '%s'", config.Port)
+	}
+	if expected := filepath.Join(dir, "server", "openvpn-status.log"); config.StatusFile != expected {
+		t.Errorf("Expected StatusFile '%s', got '%s'", expected, config.StatusFile)
+	}
+	if config.ID != "openvpn-status" {
+		t.Errorf("Expected ID 'openvpn-status', got '%s'", config.ID)
+	}
+	if config.LogFile != "/var/log/openvpn/server.log" {
+		t.Errorf("Expected LogFile '/var/log/openvpn/server.log', got '%s'", config.LogFile)
+	}
+	if expected := filepath.Join(dir, "server", "ccd"); config.ClientConfigDir != expected {
+		t.Errorf("Expected ClientConfigDir '%s', got '%s'", expected, config.ClientConfigDir)
+	}
+}
+
+// TestParseConfigCdAfterStatus tests that files are resolved against the
+// final directory, as OpenVPN opens them after reading all options
+func TestParseConfigCdAfterStatus(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"server.conf": "status status.log\ncd /etc/openvpn\ncd server\n",
+	})
+
+	config, err := ParseConfig(filepath.Join(dir, "server.conf"))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if config.StatusFile != "/etc/openvpn/server/status.log" {
+		t.Errorf("Expected StatusFile '/etc/openvpn/server/status.log', got '%s'", config.StatusFile)
+	}
+}
+
+// TestParseConfigWorkingDir tests overriding the assumed working directory
+func TestParseConfigWorkingDir(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"server/server.conf": "status status.log\nconfig server/common.conf\n",
+		"server/common.conf": "log server.log\n",
+	})
+
+	// As started with openvpn --cd <dir> --config server/server.conf
+	config, err := ParseConfigWithOptions(filepath.Join(dir, "server", "server.conf"), ParseOptions{WorkingDir: dir})
+	if err != nil {
+		t.Fatalf("ParseConfigWithOptions failed: %v", err)
+	}
+	if expected := filepath.Join(dir, "status.log"); config.StatusFile != expected {
+		t.Errorf("Expected StatusFile '%s', got '%s'", expected, config.StatusFile)
+	}
+	if expected := filepath.Join(dir, "server.log"); config.LogFile != expected {
+		t.Errorf("Expected LogFile '%s', got '%s'", expected, config.LogFile)
+	}
+
+	// The include is not found relative to the config file
+	if _, err := ParseConfig(filepath.Join(dir, "server", "server.conf")); err == nil {
+		t.Error("Expected error for include relative to another working directory")
+	}
+}
+
+// TestParseConfigIncludeErrors tests include cycles and missing includes
+func TestParseConfigIncludeErrors(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"server.conf":  "status status.log\nconfig a.conf\n",
+		"a.conf":       "config b.conf\n",
+		"b.conf":       "config ./a.conf\n",
+		"missing.conf": "status status.log\nconfig nonexistent.conf\n",
+		"twice.conf":   "status status.log\nconfig common.conf\nconfig common.conf\n",
+		"common.conf":  "dev tun\n",
+	})
+
+	_, err := ParseConfig(filepath.Join(dir, "server.conf"))
+	if err == nil || !strings.Contains(err.Error(), "config include cycle") {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+
+	_, err = ParseConfig(filepath.Join(dir, "missing.conf"))
+	if err == nil || !strings.Contains(err.Error(), "missing.conf:2") {
+		t.Errorf("Expected error naming the config directive, got %v", err)
+	}
+
+	// Including the same file twice is not a cycle
+	if _, err := ParseConfig(filepath.Join(dir, "twice.conf")); err != nil {
+		t.Errorf("Expected no error for repeated include, got %v", err)
+	}
+}
+
+// TestParseConfigQuoting tests quoted paths, the --option form and
+// inline files
+func TestParseConfigQuoting(t *testing.T) {
+	content := `--port 1195
+status "/var/log/open vpn/status.log" 30 # refreshed every 30s
+log /var/log/open\ vpn/server.log
+crl-verify '/etc/openvpn/crl\dir' dir
+<tls-crypt>
+-----BEGIN OpenVPN Static key V1-----
+port 9999
+-----END OpenVPN Static key V1-----
+</tls-crypt>
+<ca>
+dev tap
+  </ca>
+dev tun
+`
+
+	tmpfile := createTempFile(t, "server-quoting-*.conf", content)
+	defer os.Remove(tmpfile)
+
+	config, err := ParseConfig(tmpfile)
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	if config.Port != "1195" {
+		t.Errorf("Expected Port '1195', got '%s'", config.Port)
+	}
+	if config.Dev != "tun" {
+		t.Errorf("Expected Dev 'tun', got '%s'", config.Dev)
+	}
+	if config.StatusFile != "/var/log/open vpn/status.log" {
+		t.Errorf("Expected StatusFile '/var/log/open vpn/status.log', got '%s'", config.StatusFile)
+	}
+	if config.LogFile != "/var/log/open vpn/server.log" {
+		t.Errorf("Expected LogFile '/var/log/open vpn/server.log', got '%s'", config.LogFile)
+	}
+	if config.CRLFile != `/etc/openvpn/crl\dir` {
+		t.Errorf("Expected CRLFile '/etc/openvpn/crl\\dir', got '%s'", config.CRLFile)
+	}
+
+	// Inline files are single directives at the line of their opening tag
+	var names []string
+	for _, d := range config.Directives {
+		names = append(names, d.Name)
+	}
+	if strings.Join(names, " ") != "port status log crl-verify tls-crypt ca dev" {
+		t.Errorf("Expected directives port status log crl-verify tls-crypt ca dev, got %v", names)
+	}
+	if d := config.Directives[4]; !d.Inline || d.Line != 5 || d.File != tmpfile {
+		t.Errorf("Expected inline tls-crypt at %s:5, got %+v", tmpfile, d)
+	}
+	if d := config.Directives[6]; d.Line != 13 {
+		t.Errorf("Expected dev at line 13, got %d", d.Line)
+	}
+}
+
+// TestReadDirectives tests OpenVPN's tokenization rules
+func TestReadDirectives(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{`push "route 10.0.0.0 255.0.0.0"`, []string{"push", "route 10.0.0.0 255.0.0.0"}},
+		{`push "dhcp-option DOMAIN \"corp\""`, []string{"push", `dhcp-option DOMAIN "corp"`}},
+		{`auth-user-pass 'C:\Program Files\pass.txt'`, []string{"auth-user-pass", `C:\Program Files\pass.txt`}},
+		{`status a\\b.log`, []string{"status", `a\b.log`}},
+		{`setenv EMPTY ""`, []string{"setenv", "EMPTY", ""}},
+		{"port 1194 ;comment", []string{"port", "1194"}},
+		{"port 11#94", []string{"port", "11#94"}},
+		{"\tdev\t tun \r", []string{"dev", "tun"}},
+		{"--", []string{"--"}},
+		{"# port 1194", nil},
+	}
+	for _, test := range tests {
+		directives, err := ReadDirectives(strings.NewReader(test.line), "test.conf")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.line, err)
+			continue
+		}
+
+		var tokens []string
+		for _, d := range directives {
+			tokens = append(append(tokens, d.Name), d.Args...)
+		}
+		if strings.Join(tokens, "|") != strings.Join(test.expected, "|") || len(tokens) != len(test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.line, test.expected, tokens)
+		}
+	}
+
+	for _, content := range []string{
+		"port 1194\nstatus \"/var/log/status.log\n",
+		"status '/var/log/status.log\n",
+		"status /var/log/status.log\\\n",
+		"<ca>\n-----BEGIN CERTIFICATE-----\n",
+	} {
+		if _, err := ReadDirectives(strings.NewReader(content), "test.conf"); err == nil || !strings.HasPrefix(err.Error(), "test.conf:") {
+			t.Errorf("%q: expected error with location, got %v", content, err)
+		}
+	}
+}
+
 // TestParseConfigNoStatus tests error when no status directive found
 func TestParseConfigNoStatus(t *testing.T) {
 	content := `local 192.168.1.100
@@ -161,6 +431,9 @@ func TestParseConfigStatusVersion(t *testing.T) {
 		if config.StatusVersion != tt.expected {
 			t.Errorf("Expected StatusVersion %d, got %d", tt.expected, config.StatusVersion)
 		}
+		if !config.StatusVersionSet {
+			t.Errorf("Expected StatusVersionSet for version %s", tt.version)
+		}
 	}
 }
 
@@ -182,6 +455,9 @@ func TestParseConfigStatusVersionInvalid(t *testing.T) {
 		if config.StatusVersion != 3 {
 			t.Errorf("Expected default StatusVersion 3 for invalid value '%s', got %d", version, config.StatusVersion)
 		}
+		if config.StatusVersionSet {
+			t.Errorf("Expected StatusVersionSet false for invalid value '%s'", version)
+		}
 	}
 }
 
@@ -334,6 +610,24 @@ func createTempFile(t *testing.T, pattern, content string) string {
 	return tmpfile.Name()
 }
 
+// writeConfigFiles creates the given files, by slash-separated path, in a
+// temporary directory and returns the directory
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
 // BenchmarkParseConfig benchmarks config file parsing
 func BenchmarkParseConfig(b *testing.B) {
 	content := `local 192.168.1.100
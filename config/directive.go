@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Directive is an option read from an OpenVPN config file
+type Directive struct {
+	// Name is the option name, without the "--" of the command line form
+	Name string
+
+	// Args are the parameters after the name, unquoted and unescaped
+	Args []string
+
+	// Inline is true for an inline file such as <ca>...</ca>. Its content
+	// is not kept as it is often a private key.
+	Inline bool
+
+	// File is the config file the directive was read from
+	File string
+
+	// Line is the line number of the directive, the opening tag for
+	// inline files
+	Line int
+}
+
+// String returns the location of the directive as file:line
+func (d Directive) String() string {
+	return fmt.Sprintf("%s:%d", d.File, d.Line)
+}
+
+// ReadDirectives reads the directives of a single config file from r,
+// without following config includes. file is used for Directive.File and
+// in errors.
+//
+// Lines are split like OpenVPN does:
+// - parameters are separated by whitespace
+// - "..." quotes a parameter, backslash escapes work inside
+// - '...' quotes a parameter literally
+// - a backslash outside single quotes escapes the next character
+// - # or ; at the start of a parameter comments out the rest of the line
+// - a leading "--" of the option name is dropped, as in "--port 1194"
+// - a line of just <name> starts an inline file ending at </name>
+func ReadDirectives(r io.Reader, file string) ([]Directive, error) {
+	var directives []Directive
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+
+		tokens, err := splitLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, lineNum, err)
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+
+		d := Directive{Name: tokens[0], Args: tokens[1:], File: file, Line: lineNum}
+		if len(d.Name) >= 3 && strings.HasPrefix(d.Name, "--") {
+			d.Name = d.Name[2:]
+		}
+
+		// Skip the content of inline files, it must not be read as directives
+		if len(tokens) == 1 && len(d.Name) > 2 && d.Name[0] == '<' && d.Name[len(d.Name)-1] == '>' {
+			d.Name = d.Name[1 : len(d.Name)-1]
+			d.Inline = true
+
+			closeTag := "</" + d.Name + ">"
+			closed := false
+			for !closed && scanner.Scan() {
+				lineNum++
+				closed = strings.HasPrefix(strings.TrimSpace(scanner.Text()), closeTag)
+			}
+			if !closed && scanner.Err() == nil {
+				return nil, fmt.Errorf("%s:%d: missing %s", file, d.Line, closeTag)
+			}
+		}
+
+		directives = append(directives, d)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	return directives, nil
+}
+
+// splitLine splits a config line into its parameters, following the
+// rules of parse_line in OpenVPN's options.c.
+func splitLine(line string) ([]string, error) {
+	const (
+		stateInitial = iota
+		stateUnquoted
+		stateQuoted
+		stateSingleQuoted
+	)
+
+	var tokens []string
+	var token strings.Builder
+	state := stateInitial
+	backslash := false
+
+	for _, c := range line {
+		if !backslash && c == '\\' && state != stateSingleQuoted {
+			backslash = true
+			continue
+		}
+
+		done := false
+		switch state {
+		case stateInitial:
+			if isSpace(c) {
+				break
+			}
+			if c == '#' || c == ';' {
+				// Comment, even after a backslash as in OpenVPN
+				return tokens, nil
+			}
+			switch {
+			case !backslash && c == '"':
+				state = stateQuoted
+			case !backslash && c == '\'':
+				state = stateSingleQuoted
+			default:
+				token.WriteRune(c)
+				state = stateUnquoted
+			}
+
+		case stateUnquoted:
+			if !backslash && isSpace(c) {
+				done = true
+			} else {
+				token.WriteRune(c)
+			}
+
+		case stateQuoted:
+			if !backslash && c == '"' {
+				done = true
+			} else {
+				token.WriteRune(c)
+			}
+
+		case stateSingleQuoted:
+			if c == '\'' {
+				done = true
+			} else {
+				token.WriteRune(c)
+			}
+		}
+
+		if done {
+			tokens = append(tokens, token.String())
+			token.Reset()
+			state = stateInitial
+		}
+		backslash = false
+	}
+
+	// The end of the line ends an unquoted parameter
+	switch state {
+	case stateUnquoted:
+		if backslash {
+			return nil, fmt.Errorf("backslash at end of line")
+		}
+		tokens = append(tokens, token.String())
+	case stateQuoted:
+		return nil, fmt.Errorf("no closing quotation (\")")
+	case stateSingleQuoted:
+		return nil, fmt.Errorf("no closing single quotation (')")
+	}
+
+	return tokens, nil
+}
+
+// isSpace reports whether c is whitespace as in C's isspace
+func isSpace(c rune) bool {
+	switch c {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
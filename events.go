@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"openvpn-status-parser/management"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runEvents implements the events subcommand: it prints client events
+// from the management interface as JSON lines until interrupted.
+// It returns the exit code.
+func runEvents(args []string) int {
+	fs := flag.NewFlagSet("events", flag.ExitOnError)
+	managementAddr := fs.String("management", "", "Management interface address, host:port or unix socket path (required)")
+	managementPasswordFile := fs.String("management-password-file", "", "File with the management interface password")
+	byteCount := fs.Int("bytecount", 0, "Report byte counters of every client each N seconds (0 = off)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Prints client connect, disconnect and byte count events as JSON lines\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s events [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample:\n")
+		fmt.Fprintf(os.Stderr, "  %s events -management /run/openvpn/server.sock -bytecount 60\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *managementAddr == "" {
+		fmt.Fprintf(os.Stderr, "Error: -management flag is required\n\n")
+		fs.Usage()
+		return 1
+	}
+
+	mgmt, err := dialManagement(*managementAddr, *managementPasswordFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer mgmt.Close()
+
+	// Run until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sub, err := mgmt.Events(ctx, management.EventOptions{ByteCountInterval: *byteCount})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to subscribe to events: %v\n", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for event := range sub.Events {
+		if err := enc.Encode(event); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write output: %v\n", err)
+			return 1
+		}
+	}
+
+	if err := sub.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -10,3 +10,17 @@ type Formatter interface {
 	// Returns an error if formatting fails.
 	Format(status *parser.Status) (string, error)
 }
+
+// StreamFormatter writes output while a status file is being parsed.
+// It is passed as the handler to parser.ParseStream, so clients and routes
+// are formatted one by one without holding the whole Status in memory.
+// Formats that cannot write a row as soon as it is parsed keep only what
+// they need of it until Finish.
+type StreamFormatter interface {
+	parser.Handler
+
+	// Finish writes the rest of the output once parsing is done.
+	// status is the Status returned by parser.ParseStream, without
+	// client list and routing table.
+	Finish(status *parser.Status) error
+}
@@ -2,7 +2,12 @@ package formatter
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
 	"openvpn-status-parser/parser"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -86,6 +91,89 @@ func TestJSONFormatterEmptyOptionalFields(t *testing.T) {
 	}
 }
 
+// TestJSONFormatterIncludeUnknown tests that unknown records are only written on request
+func TestJSONFormatterIncludeUnknown(t *testing.T) {
+	status := createTestStatus()
+	status.Unknown = []parser.UnknownRecord{{Type: "NEW_RECORD", Fields: []string{"a"}, Line: 2}}
+
+	formatter := NewJSONFormatter(false)
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("JSON formatting failed: %v", err)
+	}
+	if strings.Contains(output, "NEW_RECORD") {
+		t.Error("Unknown records should be omitted by default")
+	}
+	if len(status.Unknown) != 1 {
+		t.Error("Formatting should not modify the status")
+	}
+
+	formatter.IncludeUnknown = true
+	output, err = formatter.Format(status)
+	if err != nil {
+		t.Fatalf("JSON formatting failed: %v", err)
+	}
+	if !strings.Contains(output, `"unknown":[{"type":"NEW_RECORD","fields":["a"],"line":2}]`) {
+		t.Errorf("Expected unknown records in output, got: %s", output)
+	}
+}
+
+// TestJSONStreamFormatter tests that streamed JSON is the same document as JSONFormatter output.
+// Key order differs as the lists are written first.
+func TestJSONStreamFormatter(t *testing.T) {
+	for _, indent := range []bool{false, true} {
+		for _, status := range []*parser.Status{createTestStatus(), {Server: &parser.ServerConfig{ID: "empty"}, ClientList: []parser.Client{}}} {
+			expected, err := NewJSONFormatter(indent).Format(status)
+			if err != nil {
+				t.Fatalf("JSON formatting failed: %v", err)
+			}
+
+			var sb strings.Builder
+			stream := NewJSONStreamFormatter(&sb, indent)
+			for _, client := range status.ClientList {
+				if err := stream.HandleClient(client); err != nil {
+					t.Fatalf("HandleClient failed: %v", err)
+				}
+			}
+			for _, route := range status.RoutingTable {
+				if err := stream.HandleRoute(route); err != nil {
+					t.Fatalf("HandleRoute failed: %v", err)
+				}
+			}
+			trailer := *status
+			trailer.ClientList, trailer.RoutingTable = nil, nil
+			if err := stream.Finish(&trailer); err != nil {
+				t.Fatalf("Finish failed: %v", err)
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal([]byte(sb.String()), &got); err != nil {
+				t.Fatalf("Streamed output (indent=%v) is not valid JSON: %v\n%s", indent, err, sb.String())
+			}
+			json.Unmarshal([]byte(expected), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Streamed JSON (indent=%v) differs:\n%s\nexpected:\n%s", indent, sb.String(), expected)
+			}
+			if indent != strings.Contains(sb.String(), "\n") {
+				t.Errorf("Streamed JSON (indent=%v) has wrong indentation:\n%s", indent, sb.String())
+			}
+		}
+	}
+}
+
+// TestJSONStreamFormatterClientAfterRoute tests that clients after routes are rejected
+func TestJSONStreamFormatterClientAfterRoute(t *testing.T) {
+	var sb strings.Builder
+	stream := NewJSONStreamFormatter(&sb, false)
+
+	if err := stream.HandleRoute(parser.Route{CommonName: "user1"}); err != nil {
+		t.Fatalf("HandleRoute failed: %v", err)
+	}
+	if err := stream.HandleClient(parser.Client{CommonName: "user1"}); err == nil {
+		t.Error("Expected error for client after routing table, got none")
+	}
+}
+
 // TestOpenMetricsFormatter tests OpenMetrics output format
 func TestOpenMetricsFormatter(t *testing.T) {
 	status := createTestStatus()
@@ -188,6 +276,394 @@ func TestOpenMetricsFormatterLabelEscaping(t *testing.T) {
 	}
 }
 
+// TestOpenMetricsFormatterGlobalStats tests server-level global stats gauges
+func TestOpenMetricsFormatterGlobalStats(t *testing.T) {
+	dco := true
+	status := createTestStatus()
+	status.GlobalStats = &parser.GlobalStats{
+		MaxBcastMcastQueueLength: 12,
+		DCOEnabled:               &dco,
+		Extra:                    map[string]string{"future_stat": "3", "not_a_number": "abc"},
+	}
+
+	formatter := NewOpenMetricsFormatter()
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	expected := []string{
+		`openvpn_server_max_bcast_mcast_queue_length{server_id="test-server"} 12`,
+		`openvpn_server_dco_enabled{server_id="test-server"} 1`,
+		`openvpn_server_global_stat{server_id="test-server",name="future_stat"} 3`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("Output should contain '%s'", line)
+		}
+	}
+
+	if strings.Contains(output, "not_a_number") {
+		t.Error("Non-numeric global stats should not be exported")
+	}
+}
+
+// TestOpenMetricsFormatterEndpointLabels tests optional real_port and transport labels
+func TestOpenMetricsFormatterEndpointLabels(t *testing.T) {
+	status := createTestStatus()
+	status.ClientList[0].RealAddress = "udp4:192.168.1.100:54321"
+	status.ClientList[0].RealEndpoint = &parser.Endpoint{
+		Transport: "udp4",
+		Addr:      netip.MustParseAddr("192.168.1.100"),
+		Port:      54321,
+	}
+
+	formatter := NewOpenMetricsFormatter()
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	if !strings.Contains(output, `real_address="192.168.1.100",`) {
+		t.Error("real_address label should contain the IP address only")
+	}
+	if strings.Contains(output, "real_port=") || strings.Contains(output, "transport=") {
+		t.Error("Endpoint labels should be off by default")
+	}
+
+	formatter.EndpointLabels = true
+	output, err = formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	if !strings.Contains(output, `real_port="54321",transport="udp4"`) {
+		t.Error("Output should contain real_port and transport labels")
+	}
+}
+
+// TestOpenMetricsFormatterComplete tests the status completeness gauge
+func TestOpenMetricsFormatterComplete(t *testing.T) {
+	status := createTestStatus()
+	formatter := NewOpenMetricsFormatter()
+
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+	if !strings.Contains(output, `openvpn_status_complete{server_id="test-server"} 0`) {
+		t.Error("Output should report incomplete status")
+	}
+
+	status.Complete = true
+	output, err = formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+	if !strings.Contains(output, `openvpn_status_complete{server_id="test-server"} 1`) {
+		t.Error("Output should report complete status")
+	}
+}
+
+// TestOpenMetricsFormatterErrorCounts tests the parse error metric
+func TestOpenMetricsFormatterErrorCounts(t *testing.T) {
+	status := createTestStatus()
+	formatter := NewOpenMetricsFormatter()
+
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+	if strings.Contains(output, "openvpn_status_parse_errors") {
+		t.Error("Output should not contain parse errors metric without errors")
+	}
+
+	status.ErrorCounts = map[string]int{"peerId": 1, "bytesReceived": 2}
+	output, err = formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+	expected := `openvpn_status_parse_errors{server_id="test-server",field="bytesReceived"} 2
+openvpn_status_parse_errors{server_id="test-server",field="peerId"} 1
+`
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected sorted parse error metrics, got: %s", output)
+	}
+}
+
+// TestOpenMetricsFormatterClientStatistics tests output for a client mode status file
+func TestOpenMetricsFormatterClientStatistics(t *testing.T) {
+	status := &parser.Status{
+		Server: &parser.ServerConfig{ID: "site-b"},
+		Statistics: &parser.ClientStatistics{
+			TunTapReadBytes:  1048576,
+			TunTapWriteBytes: 2097152,
+			LinkReadBytes:    2202009,
+			LinkWriteBytes:   1153433,
+			AuthReadBytes:    2097200,
+			Extra:            map[string]string{"pre-compress bytes": "0"},
+		},
+		Complete: true,
+	}
+
+	formatter := NewOpenMetricsFormatter()
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE openvpn_tuntap_read_bytes_total counter",
+		`openvpn_tuntap_read_bytes_total{server_id="site-b"} 1048576`,
+		`openvpn_tuntap_write_bytes_total{server_id="site-b"} 2097152`,
+		`openvpn_link_read_bytes_total{server_id="site-b"} 2202009`,
+		`openvpn_link_write_bytes_total{server_id="site-b"} 1153433`,
+		`openvpn_auth_read_bytes_total{server_id="site-b"} 2097200`,
+		`openvpn_client_statistic{server_id="site-b",name="pre-compress bytes"} 0`,
+		`openvpn_status_complete{server_id="site-b"} 1`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(output, exp) {
+			t.Errorf("Expected output to contain '%s'", exp)
+		}
+	}
+
+	if strings.Contains(output, "openvpn_clients_connected_total") {
+		t.Error("Client mode output should not contain server metrics")
+	}
+	if !strings.HasSuffix(output, "# EOF\n") {
+		t.Error("Output should end with # EOF")
+	}
+}
+
+// TestOpenMetricsFormatterManagementInfo tests metrics from the management interface
+func TestOpenMetricsFormatterManagementInfo(t *testing.T) {
+	status := createTestStatus()
+	formatter := NewOpenMetricsFormatter()
+
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+	if strings.Contains(output, "openvpn_build_info") {
+		t.Error("Output should not contain management metrics without management info")
+	}
+
+	status.Server.Management = &parser.ManagementInfo{
+		Clients:           1,
+		BytesIn:           123456,
+		BytesOut:          654321,
+		State:             "CONNECTED",
+		OpenVPNVersion:    "OpenVPN 2.6.8 x86_64-pc-linux-gnu [SSL (OpenSSL)]",
+		ManagementVersion: "5",
+	}
+	output, err = formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE openvpn_server_bytes_in_total counter",
+		`openvpn_server_bytes_in_total{server_id="test-server"} 123456`,
+		`openvpn_server_bytes_out_total{server_id="test-server"} 654321`,
+		`openvpn_server_state{server_id="test-server",state="CONNECTED"} 1`,
+		`openvpn_build_info{server_id="test-server",version="2.6.8",management_version="5"} 1`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(output, exp) {
+			t.Errorf("Expected output to contain '%s'", exp)
+		}
+	}
+}
+
+// TestOpenMetricsFormatterLogStats tests metrics from the log file
+func TestOpenMetricsFormatterLogStats(t *testing.T) {
+	status := createTestStatus()
+	status.Server.Log = &parser.LogStats{
+		Connections:        5,
+		AuthFailures:       map[string]int64{"mallory": 3, "bob": 1},
+		TLSErrors:          2,
+		Disconnects:        map[string]int64{"remote-exit": 4},
+		InactivityTimeouts: 1,
+	}
+	formatter := NewOpenMetricsFormatter()
+
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE openvpn_auth_failures_total counter",
+		`openvpn_connections_total{server_id="test-server"} 5`,
+		`openvpn_auth_failures_total{server_id="test-server",common_name="bob"} 1` + "\n" +
+			`openvpn_auth_failures_total{server_id="test-server",common_name="mallory"} 3`,
+		`openvpn_tls_errors_total{server_id="test-server"} 2`,
+		`openvpn_inactivity_timeouts_total{server_id="test-server"} 1`,
+		`openvpn_disconnects_total{server_id="test-server",reason="remote-exit"} 4`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(output, exp) {
+			t.Errorf("Expected output to contain '%s'", exp)
+		}
+	}
+}
+
+// TestOpenMetricsFormatterPool tests metrics from the ifconfig-pool-persist file
+func TestOpenMetricsFormatterPool(t *testing.T) {
+	status := createTestStatus()
+	formatter := NewOpenMetricsFormatter()
+
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+	if strings.Contains(output, "openvpn_client_address_mismatch") || strings.Contains(output, "openvpn_pool_") {
+		t.Error("Output should not contain pool metrics without pool file")
+	}
+
+	status.ClientList[1].PersistedAddress = "10.8.0.99"
+	status.ClientList[1].AddressMismatch = true
+	status.Server.Pool = &parser.PoolInfo{
+		Entries: []parser.PoolEntry{
+			{CommonName: "user1", Address: "10.8.0.2"},
+			{CommonName: "alice", Address: "10.8.0.99"},
+			{CommonName: "user3", Address: "10.8.0.99"},
+		},
+		Conflicts: []parser.PoolConflict{{Address: "10.8.0.99", CommonNames: []string{"alice", "user3"}}},
+	}
+	output, err = formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE openvpn_client_address_mismatch gauge",
+		`openvpn_client_address_mismatch{common_name="alice",`,
+		`openvpn_pool_persisted_clients{server_id="test-server"} 3`,
+		`openvpn_pool_address_conflicts{server_id="test-server"} 1`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(output, exp) {
+			t.Errorf("Expected output to contain '%s'", exp)
+		}
+	}
+	if strings.Count(output, "openvpn_client_address_mismatch{") != 1 {
+		t.Error("Expected only the flagged client in openvpn_client_address_mismatch")
+	}
+}
+
+// TestOpenMetricsFormatterCertExpiry tests the certificate expiry of clients
+func TestOpenMetricsFormatterCertExpiry(t *testing.T) {
+	status := createTestStatus()
+	formatter := NewOpenMetricsFormatter()
+
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+	if strings.Contains(output, "openvpn_client_cert_expiry_timestamp_seconds") {
+		t.Error("Output should not contain certificate expiry without known certificates")
+	}
+
+	status.ClientList[0].CertNotAfter = 1827311445
+	status.ClientList[0].CertSerial = "A"
+	output, err = formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	if !strings.Contains(output, "# TYPE openvpn_client_cert_expiry_timestamp_seconds gauge") {
+		t.Error("Expected openvpn_client_cert_expiry_timestamp_seconds TYPE line")
+	}
+	if !strings.Contains(output, `openvpn_client_cert_expiry_timestamp_seconds{common_name="user1",`) ||
+		!strings.Contains(output, "} 1827311445\n") {
+		t.Error("Expected certificate expiry 1827311445 for user1")
+	}
+	if strings.Count(output, "openvpn_client_cert_expiry_timestamp_seconds{") != 1 {
+		t.Error("Expected only clients with known certificate in openvpn_client_cert_expiry_timestamp_seconds")
+	}
+}
+
+// TestOpenMetricsFormatterCRL tests the CRL metrics and revoked clients
+func TestOpenMetricsFormatterCRL(t *testing.T) {
+	status := createTestStatus()
+	status.ClientList[1].CertRevoked = true
+	status.Server.CRL = &parser.CRLInfo{
+		ThisUpdate:         1764201600,
+		NextUpdate:         1766793600,
+		Revoked:            3,
+		Stale:              true,
+		MissingRevocations: 1,
+		RevokedConnected:   1,
+	}
+	formatter := NewOpenMetricsFormatter()
+
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE openvpn_client_cert_revoked gauge",
+		`openvpn_client_cert_revoked{common_name="alice",`,
+		`openvpn_crl_this_update_timestamp_seconds{server_id="test-server"} 1764201600`,
+		`openvpn_crl_next_update_timestamp_seconds{server_id="test-server"} 1766793600`,
+		`openvpn_crl_stale{server_id="test-server"} 1`,
+		`openvpn_crl_revoked_certificates{server_id="test-server"} 3`,
+		`openvpn_crl_missing_revocations{server_id="test-server"} 1`,
+		`openvpn_crl_revoked_clients_connected{server_id="test-server"} 1`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(output, exp) {
+			t.Errorf("Expected output to contain '%s'", exp)
+		}
+	}
+	if strings.Count(output, "openvpn_client_cert_revoked{") != 1 {
+		t.Error("Expected only the revoked client in openvpn_client_cert_revoked")
+	}
+}
+
+// TestOpenMetricsFormatterCCD tests client-config-dir metrics
+func TestOpenMetricsFormatterCCD(t *testing.T) {
+	status := createTestStatus()
+	status.ClientList[1].StaticMismatch = true
+	status.Server.CCD = &parser.CCDInfo{
+		Files: 4,
+		MissingIRoutes: []parser.MissingIRoute{
+			{CommonName: "alice", Network: "192.168.10.0/24"},
+		},
+	}
+	formatter := NewOpenMetricsFormatter()
+
+	output, err := formatter.Format(status)
+	if err != nil {
+		t.Fatalf("OpenMetrics formatting failed: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE openvpn_client_static_address_mismatch gauge",
+		`openvpn_client_static_address_mismatch{common_name="alice",`,
+		`openvpn_ccd_files{server_id="test-server"} 4`,
+		`openvpn_ccd_missing_iroute{server_id="test-server",common_name="alice",network="192.168.10.0/24"} 1`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(output, exp) {
+			t.Errorf("Expected output to contain '%s'", exp)
+		}
+	}
+	if strings.Count(output, "openvpn_client_static_address_mismatch{") != 1 {
+		t.Error("Expected only the flagged client in openvpn_client_static_address_mismatch")
+	}
+
+	// Without missing iroutes the family is omitted
+	status.Server.CCD.MissingIRoutes = nil
+	output, _ = formatter.Format(status)
+	if strings.Contains(output, "openvpn_ccd_missing_iroute") {
+		t.Error("Expected no openvpn_ccd_missing_iroute family without missing iroutes")
+	}
+}
+
 // TestOpenMetricsFormatterNoClients tests output with no clients
 func TestOpenMetricsFormatterNoClients(t *testing.T) {
 	status := &parser.Status{
@@ -352,3 +828,83 @@ func BenchmarkOpenMetricsFormatter(b *testing.B) {
 		formatter.Format(status)
 	}
 }
+
+// BenchmarkOpenMetricsFormatterLarge benchmarks OpenMetrics formatting of 20k clients
+func BenchmarkOpenMetricsFormatterLarge(b *testing.B) {
+	status := createTestStatus()
+	client := status.ClientList[0]
+	status.ClientList = make([]parser.Client, 20000)
+	for i := range status.ClientList {
+		status.ClientList[i] = client
+	}
+	formatter := NewOpenMetricsFormatter()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		formatter.Format(status)
+	}
+}
+
+// BenchmarkStreamFormatters streams 20k clients through both stream
+// formatters with ParseStream and reports the heap the formatter holds
+// once all rows are handled, before Finish, as "retained-B". The JSON
+// formatter holds nothing; the OpenMetrics formatter holds a compact
+// record per client and route, as samples have to be grouped by family.
+func BenchmarkStreamFormatters(b *testing.B) {
+	const clients = 20000
+
+	var content strings.Builder
+	content.WriteString("TITLE\tOpenVPN Server\n")
+	for i := 0; i < clients; i++ {
+		fmt.Fprintf(&content, "CLIENT_LIST\tuser%d\t192.168.1.100:%d\t10.8.%d.%d\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser%d\t%d\t%d\tAES-256-GCM\n",
+			i, 1024+i%60000, i/250, i%250+2, i, i, i)
+	}
+	for i := 0; i < clients; i++ {
+		fmt.Fprintf(&content, "ROUTING_TABLE\t10.8.%d.%d\tuser%d\t192.168.1.100:%d\tThu Nov 27 10:30:45 2025\t1732704645\n",
+			i/250, i%250+2, i, 1024+i%60000)
+	}
+	content.WriteString("END\n")
+	data := content.String()
+
+	formatters := []struct {
+		name   string
+		stream func(w io.Writer) StreamFormatter
+	}{
+		{"json", func(w io.Writer) StreamFormatter { return NewJSONStreamFormatter(w, false) }},
+		{"openmetrics", func(w io.Writer) StreamFormatter {
+			return NewOpenMetricsFormatter().NewStream(w, parser.ServerConfig{ID: "bench"})
+		}},
+	}
+	for _, f := range formatters {
+		b.Run(f.name, func(b *testing.B) {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			var sf StreamFormatter
+			var status *parser.Status
+			for i := 0; i < b.N; i++ {
+				if sf != nil {
+					sf.Finish(status)
+				}
+				sf = f.stream(io.Discard)
+				status, _ = parser.ParseStream(strings.NewReader(data), parser.ParseOptions{Version: parser.Version3}, sf)
+			}
+
+			// Measure while the last formatter still waits for Finish
+			b.StopTimer()
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			retained := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+			if retained < 0 {
+				retained = 0
+			}
+			b.ReportMetric(float64(retained), "retained-B")
+			sf.Finish(status)
+		})
+	}
+}
@@ -2,7 +2,10 @@ package formatter
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"openvpn-status-parser/parser"
+	"strings"
 )
 
 // JSONFormatter formats the status as JSON.
@@ -11,6 +14,9 @@ type JSONFormatter struct {
 	// Indent controls whether to pretty-print JSON with indentation.
 	// If true, uses 2-space indentation. If false, outputs compact JSON.
 	Indent bool
+
+	// IncludeUnknown adds records of unknown type (Status.Unknown) to the output
+	IncludeUnknown bool
 }
 
 // NewJSONFormatter creates a new JSON formatter.
@@ -24,6 +30,8 @@ func (f *JSONFormatter) Format(status *parser.Status) (string, error) {
 	var output []byte
 	var err error
 
+	status = withUnknown(status, f.IncludeUnknown)
+
 	if f.Indent {
 		// Pretty-printed JSON with 2-space indentation
 		output, err = json.MarshalIndent(status, "", "  ")
@@ -38,3 +46,176 @@ func (f *JSONFormatter) Format(status *parser.Status) (string, error) {
 
 	return string(output), nil
 }
+
+// withUnknown returns status with the unknown records dropped unless include
+// is set. The caller's Status is not modified.
+func withUnknown(status *parser.Status, include bool) *parser.Status {
+	if include || len(status.Unknown) == 0 {
+		return status
+	}
+	trimmed := *status
+	trimmed.Unknown = nil
+	return &trimmed
+}
+
+// JSONStreamFormatter writes the same JSON document as JSONFormatter while
+// the status is being parsed. Clients and routes are written as they
+// arrive; the remaining fields follow them once parsing is done.
+type JSONStreamFormatter struct {
+	w      io.Writer
+	indent bool
+
+	// IncludeUnknown adds records of unknown type (Status.Unknown) to the output
+	IncludeUnknown bool
+
+	// state tracks which list is open
+	state jsonStreamState
+
+	// count is the number of elements written to the open list
+	count int
+
+	// err is the first write error, returned by Finish
+	err error
+}
+
+// jsonStreamState is the position of a JSONStreamFormatter in its output.
+type jsonStreamState int
+
+const (
+	jsonStreamStart jsonStreamState = iota
+	jsonStreamClients
+	jsonStreamRoutes
+)
+
+// jsonStreamTrailer marshals a Status without the client list and routing
+// table, which the stream formatter has already written. The outer fields
+// shadow the embedded ones and are always omitted.
+type jsonStreamTrailer struct {
+	*parser.Status
+	ClientList   *struct{} `json:"clientList,omitempty"`
+	RoutingTable *struct{} `json:"routingTable,omitempty"`
+}
+
+// NewJSONStreamFormatter creates a JSON stream formatter writing to w.
+// If indent is true, output is indented like JSONFormatter does.
+func NewJSONStreamFormatter(w io.Writer, indent bool) *JSONStreamFormatter {
+	return &JSONStreamFormatter{w: w, indent: indent}
+}
+
+// HandleClient writes client to the clientList array.
+// Clients must not follow routes, as the routing table comes after the
+// client list in the output.
+func (f *JSONStreamFormatter) HandleClient(client parser.Client) error {
+	switch f.state {
+	case jsonStreamStart:
+		f.openList("{", "clientList")
+		f.state = jsonStreamClients
+	case jsonStreamRoutes:
+		return fmt.Errorf("client %q after routing table entries", client.CommonName)
+	}
+	f.writeElement(client)
+	return f.err
+}
+
+// HandleRoute writes route to the routingTable array.
+func (f *JSONStreamFormatter) HandleRoute(route parser.Route) error {
+	switch f.state {
+	case jsonStreamStart:
+		f.openList("{", "clientList")
+		f.closeList()
+		f.openList(",", "routingTable")
+	case jsonStreamClients:
+		f.closeList()
+		f.openList(",", "routingTable")
+	}
+	f.state = jsonStreamRoutes
+	f.writeElement(route)
+	return f.err
+}
+
+// Finish closes the open list and writes the remaining Status fields.
+func (f *JSONStreamFormatter) Finish(status *parser.Status) error {
+	if f.state == jsonStreamStart {
+		f.openList("{", "clientList")
+	}
+	f.closeList()
+
+	status = withUnknown(status, f.IncludeUnknown)
+
+	var trailer []byte
+	var err error
+	if f.indent {
+		trailer, err = json.MarshalIndent(jsonStreamTrailer{Status: status}, "", "  ")
+	} else {
+		trailer, err = json.Marshal(jsonStreamTrailer{Status: status})
+	}
+	if err != nil {
+		return err
+	}
+
+	// trailer is a complete object, join its fields to ours
+	if string(trailer) == "{}" {
+		f.write(f.newline(0) + "}")
+	} else {
+		f.write("," + string(trailer[1:]))
+	}
+	return f.err
+}
+
+// openList writes sep and the opening of the array named name.
+func (f *JSONStreamFormatter) openList(sep, name string) {
+	space := ""
+	if f.indent {
+		space = " "
+	}
+	f.write(sep + f.newline(1) + `"` + name + `":` + space + "[")
+	f.count = 0
+}
+
+// closeList writes the end of the open array.
+func (f *JSONStreamFormatter) closeList() {
+	if f.count > 0 {
+		f.write(f.newline(1))
+	}
+	f.write("]")
+}
+
+// writeElement writes v as the next element of the open array.
+func (f *JSONStreamFormatter) writeElement(v interface{}) {
+	var data []byte
+	var err error
+	if f.indent {
+		data, err = json.MarshalIndent(v, "    ", "  ")
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		if f.err == nil {
+			f.err = err
+		}
+		return
+	}
+
+	sep := ""
+	if f.count > 0 {
+		sep = ","
+	}
+	f.write(sep + f.newline(2) + string(data))
+	f.count++
+}
+
+// newline returns a line break indented to depth, or "" for compact output.
+func (f *JSONStreamFormatter) newline(depth int) string {
+	if !f.indent {
+		return ""
+	}
+	return "\n" + strings.Repeat("  ", depth)
+}
+
+// write writes s unless an earlier write failed.
+func (f *JSONStreamFormatter) write(s string) {
+	if f.err != nil {
+		return
+	}
+	_, f.err = io.WriteString(f.w, s)
+}
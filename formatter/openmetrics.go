@@ -2,14 +2,22 @@ package formatter
 
 import (
 	"fmt"
+	"io"
 	"openvpn-status-parser/parser"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // OpenMetricsFormatter formats the status as OpenMetrics/Prometheus exposition format.
 // See: https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md
-type OpenMetricsFormatter struct{}
+type OpenMetricsFormatter struct {
+	// EndpointLabels adds real_port and transport labels to client and
+	// routing metrics, e.g. to tell apart users behind the same NAT.
+	// Off by default as the source port makes every reconnect a new series.
+	EndpointLabels bool
+}
 
 // NewOpenMetricsFormatter creates a new OpenMetrics formatter.
 func NewOpenMetricsFormatter() *OpenMetricsFormatter {
@@ -23,82 +31,568 @@ func NewOpenMetricsFormatter() *OpenMetricsFormatter {
 // - Client connected status (gauge, always 1)
 // - Total clients/routes (gauges)
 // - Routing last reference time (gauge)
+// - Global stats: bcast/mcast queue length, DCO (gauges)
+// - Status file completeness (gauge)
 // - Status info (info metric)
 func (f *OpenMetricsFormatter) Format(status *parser.Status) (string, error) {
 	var sb strings.Builder
 
-	// Current time for duration calculations
-	now := time.Now().Unix()
+	stream := f.NewStream(&sb, *status.Server)
+	for _, client := range status.ClientList {
+		stream.HandleClient(client)
+	}
+	for _, route := range status.RoutingTable {
+		stream.HandleRoute(route)
+	}
+	if err := stream.Finish(status); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// OpenMetricsStreamFormatter writes OpenMetrics output for clients and
+// routes as they are parsed. OpenMetrics does not allow samples of
+// different metric families to interleave, so nothing can be written
+// before the last client is seen. Instead of the parsed rows, a compact
+// record with the rendered label set and the values of each client and
+// route is kept until Finish, which writes the families one after the
+// other in small chunks. Memory still grows with the number of clients,
+// but by a fraction of what the Status or the rendered output would need.
+type OpenMetricsStreamFormatter struct {
+	f      *OpenMetricsFormatter
+	w      io.Writer
+	server parser.ServerConfig
 
-	server := *status.Server
+	// now is the current time for duration calculations
+	now int64
 
-	// Write metric metadata and values
+	// Records of the clients and routes seen so far
+	clients []clientSample
+	routes  []routeSample
+}
+
+// clientSample is what Finish needs to write the samples of a client
+type clientSample struct {
+	labels          string
+	bytesReceived   int64
+	bytesSent       int64
+	connectedSince  int64
+	certNotAfter    int64
+	addressMismatch bool
+	certRevoked     bool
+	staticMismatch  bool
+}
+
+// routeSample is what Finish needs to write the samples of a route
+type routeSample struct {
+	labels  string
+	lastRef int64
+}
 
+// clientFamily is a metric family with a sample per client
+type clientFamily struct {
+	name string
+	typ  string
+	help string
+
+	// optional families are omitted entirely if no client has a sample
+	optional bool
+
+	// value returns the sample value of a client, false if it has none
+	value func(c *clientSample, now int64) (int64, bool)
+}
+
+// clientFamilies are the per-client metric families in output order
+var clientFamilies = []clientFamily{
 	// 1. Client bytes received (counter)
-	sb.WriteString("# HELP openvpn_client_bytes_received_total Total bytes received from client\n")
-	sb.WriteString("# TYPE openvpn_client_bytes_received_total counter\n")
-	for _, client := range status.ClientList {
-		labels := f.buildClientLabels(client, server)
-		sb.WriteString(fmt.Sprintf("openvpn_client_bytes_received_total%s %d\n", labels, client.BytesReceived))
+	{
+		name: "openvpn_client_bytes_received_total", typ: "counter",
+		help:  "Total bytes received from client",
+		value: func(c *clientSample, now int64) (int64, bool) { return c.bytesReceived, true },
+	},
+	// 2. Client bytes sent (counter)
+	{
+		name: "openvpn_client_bytes_sent_total", typ: "counter",
+		help:  "Total bytes sent to client",
+		value: func(c *clientSample, now int64) (int64, bool) { return c.bytesSent, true },
+	},
+	// 3. Client connection duration (gauge), only if the connect time is known
+	{
+		name: "openvpn_client_connected_duration_seconds", typ: "gauge", optional: true,
+		help: "Time in seconds since client connected",
+		value: func(c *clientSample, now int64) (int64, bool) {
+			return now - c.connectedSince, c.connectedSince != 0
+		},
+	},
+	// 4. Client connected indicator (gauge, always 1 since they're in the status file)
+	{
+		name: "openvpn_client_connected", typ: "gauge",
+		help:  "Client connection status (1 = connected)",
+		value: func(c *clientSample, now int64) (int64, bool) { return 1, true },
+	},
+	// 5. Virtual address differs from the persisted one (gauge), only flagged clients
+	{
+		name: "openvpn_client_address_mismatch", typ: "gauge", optional: true,
+		help:  "Client virtual address differs from the ifconfig-pool-persist file (1 = differs)",
+		value: func(c *clientSample, now int64) (int64, bool) { return 1, c.addressMismatch },
+	},
+	// 6. Certificate expiry time (gauge), only if the certificate is known
+	{
+		name: "openvpn_client_cert_expiry_timestamp_seconds", typ: "gauge", optional: true,
+		help:  "Unix time the client certificate expires",
+		value: func(c *clientSample, now int64) (int64, bool) { return c.certNotAfter, c.certNotAfter != 0 },
+	},
+	// 7. Connected with a revoked certificate (gauge), only flagged clients
+	{
+		name: "openvpn_client_cert_revoked", typ: "gauge", optional: true,
+		help:  "Client is connected with a revoked certificate (1 = revoked)",
+		value: func(c *clientSample, now int64) (int64, bool) { return 1, c.certRevoked },
+	},
+	// 8. Virtual address differs from the client-config-dir (gauge), only flagged clients
+	{
+		name: "openvpn_client_static_address_mismatch", typ: "gauge", optional: true,
+		help:  "Client virtual address differs from the client-config-dir ifconfig-push (1 = differs)",
+		value: func(c *clientSample, now int64) (int64, bool) { return 1, c.staticMismatch },
+	},
+}
+
+// chunkSize is the amount of output Finish buffers before writing it out
+const chunkSize = 64 * 1024
+
+// NewStream creates a stream formatter writing to w with the settings of f.
+// server provides the server_id label for every sample.
+func (f *OpenMetricsFormatter) NewStream(w io.Writer, server parser.ServerConfig) *OpenMetricsStreamFormatter {
+	return &OpenMetricsStreamFormatter{
+		f:      f,
+		w:      w,
+		server: server,
+		now:    time.Now().Unix(),
 	}
+}
 
-	// 2. Client bytes sent (counter)
-	sb.WriteString("# HELP openvpn_client_bytes_sent_total Total bytes sent to client\n")
-	sb.WriteString("# TYPE openvpn_client_bytes_sent_total counter\n")
-	for _, client := range status.ClientList {
-		labels := f.buildClientLabels(client, server)
-		sb.WriteString(fmt.Sprintf("openvpn_client_bytes_sent_total%s %d\n", labels, client.BytesSent))
+// HandleClient records the labels and values of client.
+func (s *OpenMetricsStreamFormatter) HandleClient(client parser.Client) error {
+	s.clients = append(s.clients, clientSample{
+		labels:          s.f.buildClientLabels(client, s.server),
+		bytesReceived:   client.BytesReceived,
+		bytesSent:       client.BytesSent,
+		connectedSince:  client.ConnectedSinceTime,
+		certNotAfter:    client.CertNotAfter,
+		addressMismatch: client.AddressMismatch,
+		certRevoked:     client.CertRevoked,
+		staticMismatch:  client.StaticMismatch,
+	})
+	return nil
+}
+
+// HandleRoute records the labels and values of a routing table entry.
+func (s *OpenMetricsStreamFormatter) HandleRoute(route parser.Route) error {
+	s.routes = append(s.routes, routeSample{
+		labels:  s.f.buildRouteLabels(route, s.server),
+		lastRef: route.LastRefTime,
+	})
+	return nil
+}
+
+// Finish writes all metric families followed by the EOF marker.
+func (s *OpenMetricsStreamFormatter) Finish(status *parser.Status) error {
+	var sb strings.Builder
+
+	labels := []string{
+		s.f.label("server_id", s.server.ID),
 	}
 
-	// 3. Client connection duration (gauge)
-	sb.WriteString("# HELP openvpn_client_connected_duration_seconds Time in seconds since client connected\n")
-	sb.WriteString("# TYPE openvpn_client_connected_duration_seconds gauge\n")
-	for _, client := range status.ClientList {
-		labels := f.buildClientLabels(client, server)
-		duration := now - client.ConnectedSinceTime
-		sb.WriteString(fmt.Sprintf("openvpn_client_connected_duration_seconds%s %d\n", labels, duration))
+	// 1-12. Client, routing and global stats families, or the byte
+	// counters of a client mode status file, which has no clients
+	if status.Statistics != nil {
+		s.f.writeStatistics(&sb, status.Statistics, labels)
+	} else if err := s.writeServerMetrics(&sb, status, labels); err != nil {
+		return err
 	}
+	s.clients, s.routes = nil, nil
 
-	// 4. Client connected indicator (gauge, always 1 since they're in the status file)
-	sb.WriteString("# HELP openvpn_client_connected Client connection status (1 = connected)\n")
-	sb.WriteString("# TYPE openvpn_client_connected gauge\n")
-	for _, client := range status.ClientList {
-		labels := f.buildClientLabels(client, server)
-		sb.WriteString(fmt.Sprintf("openvpn_client_connected%s 1\n", labels))
+	// 13. Server state from the management interface, if read from it
+	if s.server.Management != nil {
+		s.f.writeManagementInfo(&sb, s.server.Management, labels)
 	}
 
-	labels := []string{
-		fmt.Sprintf("server_id=%q", f.sanitizeLabelValue(server.ID)),
+	// 14. Client lifecycle counters from the log file, if read
+	if s.server.Log != nil {
+		s.f.writeLogStats(&sb, s.server.Log, labels)
 	}
 
-	// 5. Total connected clients (gauge)
+	// 15. Persisted address pool, if read
+	if s.server.Pool != nil {
+		s.f.writePool(&sb, s.server.Pool, labels)
+	}
+
+	// 16. Certificate revocation list, if read
+	if s.server.CRL != nil {
+		s.f.writeCRL(&sb, s.server.CRL, labels)
+	}
+
+	// 17. Client config files and missing iroutes, if read
+	if s.server.CCD != nil {
+		s.f.writeCCD(&sb, s.server.CCD, labels)
+	}
+
+	// 18. Status file completeness (gauge), 0 if the END marker was missing
+	complete := 0
+	if status.Complete {
+		complete = 1
+	}
+	sb.WriteString("# HELP openvpn_status_complete Status file was read completely including the END marker (1 = complete)\n")
+	sb.WriteString("# TYPE openvpn_status_complete gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_status_complete{%s} %d\n", strings.Join(labels, ","), complete))
+
+	// 19. Parse errors by field (gauge), only when there were any
+	if len(status.ErrorCounts) > 0 {
+		s.f.writeErrorCounts(&sb, status.ErrorCounts, labels)
+	}
+
+	// 20. Status info metric (info type - gauge with value 1)
+	sb.WriteString("# HELP openvpn_status_info OpenVPN status file metadata\n")
+	sb.WriteString("# TYPE openvpn_status_info gauge\n")
+	infoLabels := s.f.buildInfoLabels(status, s.server)
+	sb.WriteString(fmt.Sprintf("openvpn_status_info%s 1\n", infoLabels))
+
+	// 21. End of metrics marker (required by OpenMetrics spec)
+	sb.WriteString("# EOF\n")
+
+	_, err := io.WriteString(s.w, sb.String())
+	return err
+}
+
+// writeServerMetrics writes the client, routing table and global stats
+// families of a server status file.
+func (s *OpenMetricsStreamFormatter) writeServerMetrics(sb *strings.Builder, status *parser.Status, labels []string) error {
+	// 1-8. Per-client families, see clientFamilies
+	for _, family := range clientFamilies {
+		if err := s.writeClientFamily(sb, family); err != nil {
+			return err
+		}
+	}
+
+	// 9. Total connected clients (gauge)
 	sb.WriteString("# HELP openvpn_clients_connected_total Total number of connected clients\n")
 	sb.WriteString("# TYPE openvpn_clients_connected_total gauge\n")
-	sb.WriteString(fmt.Sprintf("openvpn_clients_connected_total{%s} %d\n", strings.Join(labels, ","), len(status.ClientList)))
+	sb.WriteString(fmt.Sprintf("openvpn_clients_connected_total{%s} %d\n", strings.Join(labels, ","), len(s.clients)))
 
-	// 6. Total routing entries (gauge)
+	// 10. Total routing entries (gauge)
 	sb.WriteString("# HELP openvpn_routing_entries_total Total number of routing table entries\n")
 	sb.WriteString("# TYPE openvpn_routing_entries_total gauge\n")
-	sb.WriteString(fmt.Sprintf("openvpn_routing_entries_total{%s} %d\n", strings.Join(labels, ","), len(status.RoutingTable)))
+	sb.WriteString(fmt.Sprintf("openvpn_routing_entries_total{%s} %d\n", strings.Join(labels, ","), len(s.routes)))
 
-	// 7. Routing table last reference time (gauge)
+	// 11. Routing table last reference time (gauge)
 	sb.WriteString("# HELP openvpn_routing_last_ref_seconds Unix timestamp of last routing table reference\n")
 	sb.WriteString("# TYPE openvpn_routing_last_ref_seconds gauge\n")
-	for _, route := range status.RoutingTable {
-		labels := f.buildRouteLabels(route, server)
-		sb.WriteString(fmt.Sprintf("openvpn_routing_last_ref_seconds%s %d\n", labels, route.LastRefTime))
+	for i := range s.routes {
+		fmt.Fprintf(sb, "openvpn_routing_last_ref_seconds%s %d\n", s.routes[i].labels, s.routes[i].lastRef)
+		if err := s.flush(sb, chunkSize); err != nil {
+			return err
+		}
 	}
 
-	// 8. Status info metric (info type - gauge with value 1)
-	sb.WriteString("# HELP openvpn_status_info OpenVPN status file metadata\n")
-	sb.WriteString("# TYPE openvpn_status_info gauge\n")
-	infoLabels := f.buildInfoLabels(status, server)
-	sb.WriteString(fmt.Sprintf("openvpn_status_info%s 1\n", infoLabels))
+	// 12. Global stats (gauges), only when the status file reports them
+	if status.GlobalStats != nil {
+		s.f.writeGlobalStats(sb, status.GlobalStats, labels)
+	}
 
-	// 9. End of metrics marker (required by OpenMetrics spec)
-	sb.WriteString("# EOF\n")
+	return nil
+}
 
-	return sb.String(), nil
+// writeStatistics writes the byte counters of a client mode status file.
+// Unknown counters are exported as openvpn_client_statistic{name="..."}
+// when their value is numeric.
+func (f *OpenMetricsFormatter) writeStatistics(sb *strings.Builder, stats *parser.ClientStatistics, labels []string) {
+	serverLabels := strings.Join(labels, ",")
+
+	counters := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"openvpn_tuntap_read_bytes_total", "Bytes read from the TUN/TAP device", stats.TunTapReadBytes},
+		{"openvpn_tuntap_write_bytes_total", "Bytes written to the TUN/TAP device", stats.TunTapWriteBytes},
+		{"openvpn_link_read_bytes_total", "Bytes read from the TCP/UDP link", stats.LinkReadBytes},
+		{"openvpn_link_write_bytes_total", "Bytes written to the TCP/UDP link", stats.LinkWriteBytes},
+		{"openvpn_auth_read_bytes_total", "Authenticated bytes read from the link", stats.AuthReadBytes},
+	}
+	for _, c := range counters {
+		sb.WriteString(fmt.Sprintf("# HELP %s %s\n", c.name, c.help))
+		sb.WriteString(fmt.Sprintf("# TYPE %s counter\n", c.name))
+		sb.WriteString(fmt.Sprintf("%s{%s} %d\n", c.name, serverLabels, c.value))
+	}
+
+	// Sort names for stable output
+	names := make([]string, 0, len(stats.Extra))
+	for name, value := range stats.Extra {
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	sb.WriteString("# HELP openvpn_client_statistic Client mode counter not known to this exporter\n")
+	sb.WriteString("# TYPE openvpn_client_statistic gauge\n")
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("openvpn_client_statistic{%s,%s} %s\n", serverLabels, f.label("name", name), stats.Extra[name]))
+	}
+}
+
+// writeClientFamily writes a per-client family to sb, writing sb out
+// whenever it has grown to chunkSize.
+func (s *OpenMetricsStreamFormatter) writeClientFamily(sb *strings.Builder, family clientFamily) error {
+	if family.optional && !s.hasSample(family) {
+		return nil
+	}
+
+	sb.WriteString(fmt.Sprintf("# HELP %s %s\n", family.name, family.help))
+	sb.WriteString(fmt.Sprintf("# TYPE %s %s\n", family.name, family.typ))
+	for i := range s.clients {
+		c := &s.clients[i]
+		if value, ok := family.value(c, s.now); ok {
+			fmt.Fprintf(sb, "%s%s %d\n", family.name, c.labels, value)
+			if err := s.flush(sb, chunkSize); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasSample reports whether any client has a sample in family
+func (s *OpenMetricsStreamFormatter) hasSample(family clientFamily) bool {
+	for i := range s.clients {
+		if _, ok := family.value(&s.clients[i], s.now); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// flush writes the pending output in sb out and resets it once it has
+// reached size bytes.
+func (s *OpenMetricsStreamFormatter) flush(sb *strings.Builder, size int) error {
+	if sb.Len() < size {
+		return nil
+	}
+	if _, err := io.WriteString(s.w, sb.String()); err != nil {
+		return err
+	}
+	sb.Reset()
+	return nil
+}
+
+// writeGlobalStats writes server-level gauges for the GLOBAL_STATS values.
+// Unknown stats are exported as openvpn_server_global_stat{name="..."}
+// when their value is numeric.
+func (f *OpenMetricsFormatter) writeGlobalStats(sb *strings.Builder, stats *parser.GlobalStats, labels []string) {
+	serverLabels := strings.Join(labels, ",")
+
+	sb.WriteString("# HELP openvpn_server_max_bcast_mcast_queue_length Maximum length of the broadcast/multicast queue\n")
+	sb.WriteString("# TYPE openvpn_server_max_bcast_mcast_queue_length gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_server_max_bcast_mcast_queue_length{%s} %d\n", serverLabels, stats.MaxBcastMcastQueueLength))
+
+	if stats.DCOEnabled != nil {
+		dco := 0
+		if *stats.DCOEnabled {
+			dco = 1
+		}
+		sb.WriteString("# HELP openvpn_server_dco_enabled Data channel offload status (1 = enabled)\n")
+		sb.WriteString("# TYPE openvpn_server_dco_enabled gauge\n")
+		sb.WriteString(fmt.Sprintf("openvpn_server_dco_enabled{%s} %d\n", serverLabels, dco))
+	}
+
+	// Sort names for stable output
+	names := make([]string, 0, len(stats.Extra))
+	for name, value := range stats.Extra {
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	sb.WriteString("# HELP openvpn_server_global_stat Global statistic not known to this exporter\n")
+	sb.WriteString("# TYPE openvpn_server_global_stat gauge\n")
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("openvpn_server_global_stat{%s,%s} %s\n", serverLabels, f.label("name", name), stats.Extra[name]))
+	}
+}
+
+// writeManagementInfo writes the load-stats counters, the state and the
+// build info reported by the management interface.
+func (f *OpenMetricsFormatter) writeManagementInfo(sb *strings.Builder, info *parser.ManagementInfo, labels []string) {
+	serverLabels := strings.Join(labels, ",")
+
+	sb.WriteString("# HELP openvpn_server_bytes_in_total Total bytes received from all clients\n")
+	sb.WriteString("# TYPE openvpn_server_bytes_in_total counter\n")
+	sb.WriteString(fmt.Sprintf("openvpn_server_bytes_in_total{%s} %d\n", serverLabels, info.BytesIn))
+
+	sb.WriteString("# HELP openvpn_server_bytes_out_total Total bytes sent to all clients\n")
+	sb.WriteString("# TYPE openvpn_server_bytes_out_total counter\n")
+	sb.WriteString(fmt.Sprintf("openvpn_server_bytes_out_total{%s} %d\n", serverLabels, info.BytesOut))
+
+	if info.State != "" {
+		sb.WriteString("# HELP openvpn_server_state Current state of the OpenVPN process (always 1)\n")
+		sb.WriteString("# TYPE openvpn_server_state gauge\n")
+		sb.WriteString(fmt.Sprintf("openvpn_server_state{%s,%s} 1\n", serverLabels, f.label("state", info.State)))
+	}
+
+	if info.OpenVPNVersion != "" {
+		sb.WriteString("# HELP openvpn_build_info OpenVPN and management interface version (always 1)\n")
+		sb.WriteString("# TYPE openvpn_build_info gauge\n")
+		sb.WriteString(fmt.Sprintf("openvpn_build_info{%s,%s,%s} 1\n", serverLabels,
+			f.label("version", openvpnRelease(info.OpenVPNVersion)),
+			f.label("management_version", info.ManagementVersion)))
+	}
+}
+
+// openvpnRelease returns the release number from the "OpenVPN Version"
+// string, e.g. "2.6.8" from "OpenVPN 2.6.8 x86_64-pc-linux-gnu [SSL ...]".
+// Unknown formats are returned unchanged.
+func openvpnRelease(version string) string {
+	fields := strings.Fields(version)
+	if len(fields) >= 2 && fields[0] == "OpenVPN" {
+		return fields[1]
+	}
+	return version
+}
+
+// writeLogStats writes the connection, auth failure, TLS error and
+// disconnect counters read from the log file. Labeled counters are sorted
+// by label value.
+func (f *OpenMetricsFormatter) writeLogStats(sb *strings.Builder, stats *parser.LogStats, labels []string) {
+	serverLabels := strings.Join(labels, ",")
+
+	sb.WriteString("# HELP openvpn_connections_total Peer connections initiated, from the log file\n")
+	sb.WriteString("# TYPE openvpn_connections_total counter\n")
+	sb.WriteString(fmt.Sprintf("openvpn_connections_total{%s} %d\n", serverLabels, stats.Connections))
+
+	sb.WriteString("# HELP openvpn_auth_failures_total Clients sent AUTH_FAILED, from the log file\n")
+	sb.WriteString("# TYPE openvpn_auth_failures_total counter\n")
+	for _, cn := range sortedKeys(stats.AuthFailures) {
+		sb.WriteString(fmt.Sprintf("openvpn_auth_failures_total{%s,%s} %d\n", serverLabels, f.label("common_name", cn), stats.AuthFailures[cn]))
+	}
+
+	sb.WriteString("# HELP openvpn_tls_errors_total TLS errors, from the log file\n")
+	sb.WriteString("# TYPE openvpn_tls_errors_total counter\n")
+	sb.WriteString(fmt.Sprintf("openvpn_tls_errors_total{%s} %d\n", serverLabels, stats.TLSErrors))
+
+	sb.WriteString("# HELP openvpn_inactivity_timeouts_total Clients timed out for inactivity, from the log file\n")
+	sb.WriteString("# TYPE openvpn_inactivity_timeouts_total counter\n")
+	sb.WriteString(fmt.Sprintf("openvpn_inactivity_timeouts_total{%s} %d\n", serverLabels, stats.InactivityTimeouts))
+
+	sb.WriteString("# HELP openvpn_disconnects_total Client instances exiting or restarting by reason, from the log file\n")
+	sb.WriteString("# TYPE openvpn_disconnects_total counter\n")
+	for _, reason := range sortedKeys(stats.Disconnects) {
+		sb.WriteString(fmt.Sprintf("openvpn_disconnects_total{%s,%s} %d\n", serverLabels, f.label("reason", reason), stats.Disconnects[reason]))
+	}
+}
+
+// writePool writes the number of persisted addresses and of addresses
+// persisted for more than one common name.
+func (f *OpenMetricsFormatter) writePool(sb *strings.Builder, pool *parser.PoolInfo, labels []string) {
+	serverLabels := strings.Join(labels, ",")
+
+	sb.WriteString("# HELP openvpn_pool_persisted_clients Common names in the ifconfig-pool-persist file\n")
+	sb.WriteString("# TYPE openvpn_pool_persisted_clients gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_pool_persisted_clients{%s} %d\n", serverLabels, len(pool.Entries)))
+
+	sb.WriteString("# HELP openvpn_pool_address_conflicts Addresses persisted for more than one common name\n")
+	sb.WriteString("# TYPE openvpn_pool_address_conflicts gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_pool_address_conflicts{%s} %d\n", serverLabels, len(pool.Conflicts)))
+}
+
+// writeCRL writes the CRL update times, its size and the revoked
+// certificates it is missing or that are still connected.
+func (f *OpenMetricsFormatter) writeCRL(sb *strings.Builder, crl *parser.CRLInfo, labels []string) {
+	serverLabels := strings.Join(labels, ",")
+
+	if crl.ThisUpdate != 0 {
+		sb.WriteString("# HELP openvpn_crl_this_update_timestamp_seconds Unix time the CRL was issued\n")
+		sb.WriteString("# TYPE openvpn_crl_this_update_timestamp_seconds gauge\n")
+		sb.WriteString(fmt.Sprintf("openvpn_crl_this_update_timestamp_seconds{%s} %d\n", serverLabels, crl.ThisUpdate))
+	}
+
+	if crl.NextUpdate != 0 {
+		sb.WriteString("# HELP openvpn_crl_next_update_timestamp_seconds Unix time by which the CRL should be replaced\n")
+		sb.WriteString("# TYPE openvpn_crl_next_update_timestamp_seconds gauge\n")
+		sb.WriteString(fmt.Sprintf("openvpn_crl_next_update_timestamp_seconds{%s} %d\n", serverLabels, crl.NextUpdate))
+	}
+
+	stale := 0
+	if crl.Stale {
+		stale = 1
+	}
+	sb.WriteString("# HELP openvpn_crl_stale CRL is past its next update time (1 = stale)\n")
+	sb.WriteString("# TYPE openvpn_crl_stale gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_crl_stale{%s} %d\n", serverLabels, stale))
+
+	sb.WriteString("# HELP openvpn_crl_revoked_certificates Revoked certificates in the CRL\n")
+	sb.WriteString("# TYPE openvpn_crl_revoked_certificates gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_crl_revoked_certificates{%s} %d\n", serverLabels, crl.Revoked))
+
+	sb.WriteString("# HELP openvpn_crl_missing_revocations Unexpired certificates revoked in index.txt but missing from the CRL\n")
+	sb.WriteString("# TYPE openvpn_crl_missing_revocations gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_crl_missing_revocations{%s} %d\n", serverLabels, crl.MissingRevocations))
+
+	sb.WriteString("# HELP openvpn_crl_revoked_clients_connected Clients connected with a revoked certificate\n")
+	sb.WriteString("# TYPE openvpn_crl_revoked_clients_connected gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_crl_revoked_clients_connected{%s} %d\n", serverLabels, crl.RevokedConnected))
+}
+
+// writeCCD writes the number of client config files and the iroutes of
+// connected clients missing from the routing table.
+func (f *OpenMetricsFormatter) writeCCD(sb *strings.Builder, info *parser.CCDInfo, labels []string) {
+	serverLabels := strings.Join(labels, ",")
+
+	sb.WriteString("# HELP openvpn_ccd_files Client config files in the client-config-dir\n")
+	sb.WriteString("# TYPE openvpn_ccd_files gauge\n")
+	sb.WriteString(fmt.Sprintf("openvpn_ccd_files{%s} %d\n", serverLabels, info.Files))
+
+	// Omitted entirely if all iroutes are in the routing table
+	if len(info.MissingIRoutes) == 0 {
+		return
+	}
+	sb.WriteString("# HELP openvpn_ccd_missing_iroute Iroute of a connected client missing from the routing table (1 = missing)\n")
+	sb.WriteString("# TYPE openvpn_ccd_missing_iroute gauge\n")
+	for _, m := range info.MissingIRoutes {
+		sb.WriteString(fmt.Sprintf("openvpn_ccd_missing_iroute{%s,%s,%s} 1\n", serverLabels,
+			f.label("common_name", m.CommonName), f.label("network", m.Network)))
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeErrorCounts writes the number of parse errors per field, sorted by field.
+func (f *OpenMetricsFormatter) writeErrorCounts(sb *strings.Builder, counts map[string]int, labels []string) {
+	fields := make([]string, 0, len(counts))
+	for field := range counts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	sb.WriteString("# HELP openvpn_status_parse_errors Number of errors while parsing the status file, by field\n")
+	sb.WriteString("# TYPE openvpn_status_parse_errors gauge\n")
+	for _, field := range fields {
+		sb.WriteString(fmt.Sprintf("openvpn_status_parse_errors{%s,%s} %d\n", strings.Join(labels, ","), f.label("field", field), counts[field]))
+	}
 }
 
 // buildClientLabels creates label string for client metrics.
@@ -106,17 +600,19 @@ func (f *OpenMetricsFormatter) Format(status *parser.Status) (string, error) {
 // Empty optional labels (username) are omitted.
 func (f *OpenMetricsFormatter) buildClientLabels(client parser.Client, server parser.ServerConfig) string {
 	labels := []string{
-		fmt.Sprintf("common_name=%q", f.sanitizeLabelValue(client.CommonName)),
-		fmt.Sprintf("real_address=%q", f.sanitizeLabelValue(client.RealAddress)),
-		fmt.Sprintf("server_id=%q", f.sanitizeLabelValue(server.ID)),
-		fmt.Sprintf("virtual_address=%q", f.sanitizeLabelValue(client.VirtualAddress)),
+		f.label("common_name", client.CommonName),
+		f.label("real_address", realAddressLabel(client.RealAddress, client.RealEndpoint)),
+		f.label("server_id", server.ID),
+		f.label("virtual_address", client.VirtualAddress),
 	}
 
 	// Add username only if present
 	if client.Username != "" {
-		labels = append(labels, fmt.Sprintf("username=%q", f.sanitizeLabelValue(client.Username)))
+		labels = append(labels, f.label("username", client.Username))
 	}
 
+	labels = f.appendEndpointLabels(labels, client.RealEndpoint)
+
 	return "{" + strings.Join(labels, ",") + "}"
 }
 
@@ -124,34 +620,65 @@ func (f *OpenMetricsFormatter) buildClientLabels(client parser.Client, server pa
 // Format: {virtual_address="...",common_name="...",real_address="..."}
 func (f *OpenMetricsFormatter) buildRouteLabels(route parser.Route, server parser.ServerConfig) string {
 	labels := []string{
-		fmt.Sprintf("virtual_address=%q", f.sanitizeLabelValue(route.VirtualAddress)),
-		fmt.Sprintf("common_name=%q", f.sanitizeLabelValue(route.CommonName)),
-		fmt.Sprintf("real_address=%q", f.sanitizeLabelValue(route.RealAddress)),
-		fmt.Sprintf("server_id=%q", f.sanitizeLabelValue(server.ID)),
+		f.label("virtual_address", route.VirtualAddress),
+		f.label("common_name", route.CommonName),
+		f.label("real_address", realAddressLabel(route.RealAddress, route.RealEndpoint)),
+		f.label("server_id", server.ID),
 	}
+
+	labels = f.appendEndpointLabels(labels, route.RealEndpoint)
+
 	return "{" + strings.Join(labels, ",") + "}"
 }
 
+// appendEndpointLabels adds real_port and transport labels if enabled.
+// Format: real_port="...",transport="..."
+// transport is omitted when the status file has no protocol prefix.
+func (f *OpenMetricsFormatter) appendEndpointLabels(labels []string, ep *parser.Endpoint) []string {
+	if !f.EndpointLabels || ep == nil {
+		return labels
+	}
+	labels = append(labels, f.label("real_port", strconv.Itoa(int(ep.Port))))
+	if ep.Transport != "" {
+		labels = append(labels, f.label("transport", ep.Transport))
+	}
+	return labels
+}
+
+// realAddressLabel returns the IP address for the real_address label,
+// falling back to the raw string if it could not be parsed.
+func realAddressLabel(raw string, ep *parser.Endpoint) string {
+	if ep == nil {
+		return raw
+	}
+	return ep.Addr.String()
+}
+
 // buildInfoLabels creates label string for the info metric.
 // Format: {title="...",updated_at="..."}
 func (f *OpenMetricsFormatter) buildInfoLabels(status *parser.Status, server parser.ServerConfig) string {
 	labels := []string{
-		fmt.Sprintf("title=%q", f.sanitizeLabelValue(status.Title)),
-		fmt.Sprintf("server_id=%q", f.sanitizeLabelValue(server.ID)),
-		fmt.Sprintf("server_local=%q", f.sanitizeLabelValue(server.Local)),
-		fmt.Sprintf("server_port=%q", f.sanitizeLabelValue(server.Port)),
-		fmt.Sprintf("server_proto=%q", f.sanitizeLabelValue(server.Proto)),
-		fmt.Sprintf("server_dev=%q", f.sanitizeLabelValue(server.Dev)),
+		f.label("title", status.Title),
+		f.label("server_id", server.ID),
+		f.label("server_local", server.Local),
+		f.label("server_port", server.Port),
+		f.label("server_proto", server.Proto),
+		f.label("server_dev", server.Dev),
 	}
 
 	// Add timestamp if available
 	if len(status.Time) > 0 {
-		labels = append(labels, fmt.Sprintf("updated_at=%q", f.sanitizeLabelValue(status.Time[0])))
+		labels = append(labels, f.label("updated_at", status.Time[0]))
 	}
 
 	return "{" + strings.Join(labels, ",") + "}"
 }
 
+// label renders a single name="value" label pair with the value escaped.
+func (f *OpenMetricsFormatter) label(name, value string) string {
+	return name + `="` + f.sanitizeLabelValue(value) + `"`
+}
+
 // sanitizeLabelValue escapes special characters in label values.
 // OpenMetrics requires escaping backslashes, newlines, and double quotes.
 // See: https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#escaping
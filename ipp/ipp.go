@@ -0,0 +1,186 @@
+// Package ipp reads the ifconfig-pool-persist file in which OpenVPN keeps
+// the virtual addresses reserved for each common name, also while the
+// client is offline. Lines are
+//
+//	cn,10.8.0.4            (up to OpenVPN 2.4)
+//	cn,10.8.0.4,fd00::1000 (2.5+, the IPv6 column may be empty)
+package ipp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"openvpn-status-parser/parser"
+	"os"
+	"strings"
+)
+
+// ParseFile reads the ifconfig-pool-persist file at path, see Parse.
+func ParseFile(path string) (*parser.PoolInfo, []error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to open pool file: %w", err)}
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse reads an ifconfig-pool-persist file. Invalid lines are skipped and
+// returned as ParseErrors; the pool is nil only if r could not be read.
+func Parse(r io.Reader) (*parser.PoolInfo, []error) {
+	pool := &parser.PoolInfo{Entries: []parser.PoolEntry{}}
+	var parseErrors []error
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseEntry(line, lineNum)
+		if err != nil {
+			parseErrors = append(parseErrors, err)
+			continue
+		}
+		pool.Entries = append(pool.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, append(parseErrors, fmt.Errorf("error reading pool file: %w", err))
+	}
+
+	pool.Conflicts = findConflicts(pool.Entries)
+	return pool, parseErrors
+}
+
+// parseEntry parses a line of the pool file
+func parseEntry(line string, lineNum int) (parser.PoolEntry, error) {
+	fields := strings.Split(line, ",")
+	if len(fields) < 2 {
+		return parser.PoolEntry{}, parser.ParseError{
+			Line: lineNum, Field: "entry", Value: line, Kind: parser.KindShortRecord,
+			Err: fmt.Errorf("expected common name and address"),
+		}
+	}
+
+	entry := parser.PoolEntry{
+		CommonName: fields[0],
+		Address:    fields[1],
+		Line:       lineNum,
+	}
+	if len(fields) > 2 {
+		entry.IPv6Address = fields[2]
+	}
+
+	var errs parser.ParseErrors
+	if entry.Address != "" {
+		if addr, err := netip.ParseAddr(entry.Address); err != nil || !addr.Is4() {
+			errs = append(errs, parser.ParseError{
+				Line: lineNum, Field: "address", Value: entry.Address, Kind: parser.KindBadValue,
+				Err: fmt.Errorf("invalid IPv4 address"),
+			})
+		}
+	}
+	if entry.IPv6Address != "" {
+		if addr, err := netip.ParseAddr(entry.IPv6Address); err != nil || !addr.Is6() {
+			errs = append(errs, parser.ParseError{
+				Line: lineNum, Field: "ipv6Address", Value: entry.IPv6Address, Kind: parser.KindBadValue,
+				Err: fmt.Errorf("invalid IPv6 address"),
+			})
+		}
+	}
+	if entry.Address == "" && entry.IPv6Address == "" {
+		errs = append(errs, parser.ParseError{
+			Line: lineNum, Field: "address", Value: line, Kind: parser.KindBadValue,
+			Err: fmt.Errorf("no address"),
+		})
+	}
+
+	if len(errs) > 0 {
+		return entry, errs
+	}
+	return entry, nil
+}
+
+// findConflicts returns the addresses persisted for more than one common
+// name, in the order the addresses first appear.
+func findConflicts(entries []parser.PoolEntry) []parser.PoolConflict {
+	owners := make(map[string][]string)
+	var addresses []string
+	add := func(addr, cn string) {
+		if addr == "" {
+			return
+		}
+		for _, owner := range owners[addr] {
+			if owner == cn {
+				return
+			}
+		}
+		if len(owners[addr]) == 0 {
+			addresses = append(addresses, addr)
+		}
+		owners[addr] = append(owners[addr], cn)
+	}
+	for _, entry := range entries {
+		add(entry.Address, entry.CommonName)
+		add(entry.IPv6Address, entry.CommonName)
+	}
+
+	var conflicts []parser.PoolConflict
+	for _, addr := range addresses {
+		if len(owners[addr]) > 1 {
+			conflicts = append(conflicts, parser.PoolConflict{Address: addr, CommonNames: owners[addr]})
+		}
+	}
+	return conflicts
+}
+
+// Annotator sets the persisted addresses of clients from a pool.
+type Annotator struct {
+	byCommonName map[string]parser.PoolEntry
+}
+
+// NewAnnotator indexes pool by common name. If a common name has several
+// entries, the first one is used, as OpenVPN does when reading the file.
+func NewAnnotator(pool *parser.PoolInfo) *Annotator {
+	a := &Annotator{byCommonName: make(map[string]parser.PoolEntry, len(pool.Entries))}
+	for _, entry := range pool.Entries {
+		if _, ok := a.byCommonName[entry.CommonName]; !ok {
+			a.byCommonName[entry.CommonName] = entry
+		}
+	}
+	return a
+}
+
+// Annotate sets PersistedAddress, PersistedIPv6Address and AddressMismatch
+// of client. Clients without pool entry are left unchanged; addresses the
+// status file does not show (v1) are not compared.
+func (a *Annotator) Annotate(client *parser.Client) {
+	entry, ok := a.byCommonName[client.CommonName]
+	if !ok {
+		return
+	}
+
+	client.PersistedAddress = entry.Address
+	client.PersistedIPv6Address = entry.IPv6Address
+	client.AddressMismatch = differs(client.VirtualAddress, entry.Address) ||
+		differs(client.VirtualIPv6Address, entry.IPv6Address)
+}
+
+// differs reports whether the current and persisted addresses are both
+// known and not the same address
+func differs(current, persisted string) bool {
+	if current == "" || persisted == "" {
+		return false
+	}
+	cur, err1 := netip.ParseAddr(current)
+	per, err2 := netip.ParseAddr(persisted)
+	if err1 != nil || err2 != nil {
+		return current != persisted
+	}
+	return cur != per
+}
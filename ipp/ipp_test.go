@@ -0,0 +1,140 @@
+package ipp
+
+import (
+	"errors"
+	"openvpn-status-parser/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParse tests reading v4-only and v4/v6 lines
+func TestParse(t *testing.T) {
+	content := `user1,10.8.0.4
+user2,10.8.0.8,
+user3,10.8.0.12,fd00::1000
+
+user4,,fd00::1004
+`
+	pool, errs := Parse(strings.NewReader(content))
+	if len(errs) > 0 {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+
+	expected := []parser.PoolEntry{
+		{CommonName: "user1", Address: "10.8.0.4", Line: 1},
+		{CommonName: "user2", Address: "10.8.0.8", Line: 2},
+		{CommonName: "user3", Address: "10.8.0.12", IPv6Address: "fd00::1000", Line: 3},
+		{CommonName: "user4", IPv6Address: "fd00::1004", Line: 5},
+	}
+	if len(pool.Entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(pool.Entries))
+	}
+	for i, exp := range expected {
+		if pool.Entries[i] != exp {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, exp, pool.Entries[i])
+		}
+	}
+	if len(pool.Conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", pool.Conflicts)
+	}
+}
+
+// TestParseConflicts tests addresses persisted for two common names
+func TestParseConflicts(t *testing.T) {
+	content := `user1,10.8.0.4,fd00::1000
+user2,10.8.0.4,
+user3,10.8.0.12,fd00::1000
+user1,10.8.0.4,
+`
+	pool, errs := Parse(strings.NewReader(content))
+	if len(errs) > 0 {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+
+	if len(pool.Conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %+v", pool.Conflicts)
+	}
+	if c := pool.Conflicts[0]; c.Address != "10.8.0.4" || strings.Join(c.CommonNames, ",") != "user1,user2" {
+		t.Errorf("Expected 10.8.0.4 for user1,user2, got %+v", c)
+	}
+	if c := pool.Conflicts[1]; c.Address != "fd00::1000" || strings.Join(c.CommonNames, ",") != "user1,user3" {
+		t.Errorf("Expected fd00::1000 for user1,user3, got %+v", c)
+	}
+}
+
+// TestParseErrors tests that invalid lines are reported and skipped
+func TestParseErrors(t *testing.T) {
+	content := `user1
+user2,10.8.0.300
+user3,10.8.0.12,10.8.0.13
+user4,,
+user5,10.8.0.20
+`
+	pool, errs := Parse(strings.NewReader(content))
+	if len(pool.Entries) != 1 || pool.Entries[0].CommonName != "user5" {
+		t.Errorf("Expected only user5 to be read, got %+v", pool.Entries)
+	}
+
+	flat := parser.FlattenErrors(errs)
+	if len(flat) != 4 {
+		t.Fatalf("Expected 4 errors, got %d: %v", len(flat), flat)
+	}
+	if !errors.Is(flat[0], parser.ErrShortRecord) || flat[0].Field != "entry" {
+		t.Errorf("Expected short entry error for line 1, got %v", flat[0])
+	}
+	for _, err := range flat[1:] {
+		if err.Kind != parser.KindBadValue {
+			t.Errorf("Expected bad value error, got %v", err)
+		}
+	}
+}
+
+// TestParseFile tests reading the pool file from disk
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipp.txt")
+	if err := os.WriteFile(path, []byte("user1,10.8.0.4,\n"), 0644); err != nil {
+		t.Fatalf("Failed to write pool file: %v", err)
+	}
+
+	pool, errs := ParseFile(path)
+	if len(errs) > 0 {
+		t.Fatalf("ParseFile failed: %v", errs)
+	}
+	if len(pool.Entries) != 1 {
+		t.Errorf("Expected 1 entry, got %d", len(pool.Entries))
+	}
+
+	pool, errs = ParseFile(filepath.Join(t.TempDir(), "missing.txt"))
+	if pool != nil || len(errs) != 1 {
+		t.Errorf("Expected nil pool and one error for missing file, got %v, %v", pool, errs)
+	}
+}
+
+// TestAnnotate tests flagging clients whose address differs from the pool
+func TestAnnotate(t *testing.T) {
+	pool, _ := Parse(strings.NewReader("user1,10.8.0.4,fd00::1000\nuser2,10.8.0.8,\nuser2,10.8.0.99,\n"))
+	a := NewAnnotator(pool)
+
+	tests := []struct {
+		client   parser.Client
+		address  string
+		mismatch bool
+	}{
+		{parser.Client{CommonName: "user1", VirtualAddress: "10.8.0.4", VirtualIPv6Address: "fd00:0::1000"}, "10.8.0.4", false},
+		{parser.Client{CommonName: "user1", VirtualAddress: "10.8.0.4", VirtualIPv6Address: "fd00::2000"}, "10.8.0.4", true},
+		{parser.Client{CommonName: "user2", VirtualAddress: "10.8.0.12"}, "10.8.0.8", true},
+		{parser.Client{CommonName: "user2"}, "10.8.0.8", false},
+		{parser.Client{CommonName: "user3", VirtualAddress: "10.8.0.12"}, "", false},
+	}
+
+	for _, tt := range tests {
+		client := tt.client
+		a.Annotate(&client)
+		if client.PersistedAddress != tt.address || client.AddressMismatch != tt.mismatch {
+			t.Errorf("%s at %s: expected persisted '%s' mismatch %v, got '%s' %v", client.CommonName,
+				client.VirtualAddress, tt.address, tt.mismatch, client.PersistedAddress, client.AddressMismatch)
+		}
+	}
+}
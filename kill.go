@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"openvpn-status-parser/management"
+	"os"
+)
+
+// defaultAuditLog is where the kill subcommand records who killed what
+const defaultAuditLog = "/var/log/openvpn-status-parser-audit.log"
+
+// runKill implements the kill subcommand: it disconnects clients through
+// the management interface, verifies they are gone and writes an audit
+// record. It returns the exit code.
+func runKill(args []string) int {
+	fs := flag.NewFlagSet("kill", flag.ExitOnError)
+	managementAddr := fs.String("management", "", "Management interface address, host:port or unix socket path (required)")
+	managementPasswordFile := fs.String("management-password-file", "", "File with the management interface password")
+	commonName := fs.String("cn", "", "Kill all clients with this common name")
+	realAddress := fs.String("address", "", "Kill the client connected from this real address (ip:port)")
+	clientID := fs.String("client-id", "", "Kill the client with this client ID")
+	auditLog := fs.String("audit-log", defaultAuditLog, "File the audit record is appended to")
+	verifyTimeout := fs.Duration("verify-timeout", management.DefaultVerifyTimeout, "How long to wait for the client to disappear from the status")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Disconnects clients through the management interface\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s kill [options] (-cn <name> | -address <ip:port> | -client-id <id>)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample:\n")
+		fmt.Fprintf(os.Stderr, "  %s kill -management /run/openvpn/server.sock -cn laptop-42\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *managementAddr == "" {
+		fmt.Fprintf(os.Stderr, "Error: -management flag is required\n\n")
+		fs.Usage()
+		return 1
+	}
+
+	// Exactly one target
+	var targets []management.Target
+	if *commonName != "" {
+		targets = append(targets, management.Target{Kind: management.TargetCommonName, Value: *commonName})
+	}
+	if *realAddress != "" {
+		targets = append(targets, management.Target{Kind: management.TargetRealAddress, Value: *realAddress})
+	}
+	if *clientID != "" {
+		targets = append(targets, management.Target{Kind: management.TargetClientID, Value: *clientID})
+	}
+	if len(targets) != 1 {
+		fmt.Fprintf(os.Stderr, "Error: exactly one of -cn, -address and -client-id is required\n\n")
+		fs.Usage()
+		return 1
+	}
+	target := targets[0]
+
+	// Refuse to kill anything that cannot be audited
+	file, err := os.OpenFile(*auditLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot write audit log: %v\n", err)
+		return 1
+	}
+	file.Close()
+
+	mgmt, err := dialManagement(*managementAddr, *managementPasswordFile)
+	if err != nil {
+		// Failed attempts are audited too
+		management.AppendAudit(*auditLog, management.NewAuditRecord(target, nil, err))
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	result, killErr := mgmt.Kill(target, *verifyTimeout)
+	mgmt.Close()
+
+	exitCode := 0
+	if err := management.AppendAudit(*auditLog, management.NewAuditRecord(target, result, killErr)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exitCode = 1
+	}
+
+	if result != nil {
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(out))
+	}
+
+	if killErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", killErr)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Killed %d client(s) matching %s\n", len(result.Killed), target)
+	return exitCode
+}
@@ -0,0 +1,280 @@
+// Package logparser reads the connection lifecycle of OpenVPN clients from
+// the server log file written with the log or log-append directive.
+//
+// A server log line looks like
+//
+//	2025-11-27 10:30:45 user1/192.168.1.100:54321 SIGTERM[soft,remote-exit] received, client-instance exiting
+//
+// with an optional timestamp, the peer the line is about ("cn/address" or
+// just "address" before the client is authenticated) and the message. Lines
+// that are not one of the events below are skipped.
+package logparser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"openvpn-status-parser/parser"
+	"os"
+	"strings"
+	"time"
+)
+
+// EventType identifies what happened to a client
+type EventType string
+
+const (
+	// EventConnected - "Peer Connection Initiated", the TLS handshake succeeded
+	EventConnected EventType = "connected"
+
+	// EventAuthFailed - The client was sent, or received, AUTH_FAILED
+	EventAuthFailed EventType = "authFailed"
+
+	// EventTLSError - "TLS Error: ...", e.g. a failed handshake
+	EventTLSError EventType = "tlsError"
+
+	// EventInactivityTimeout - "Inactivity timeout (--ping-restart)" and similar
+	EventInactivityTimeout EventType = "inactivityTimeout"
+
+	// EventDisconnected - "client-instance exiting" or "client-instance restarting"
+	EventDisconnected EventType = "disconnected"
+)
+
+// Event is a recognized log line.
+type Event struct {
+	// Type is what happened
+	Type EventType `json:"type"`
+
+	// Time is the Unix time of the log line, 0 if the log has no timestamps
+	// (--suppress-timestamps)
+	Time int64 `json:"time,omitempty"`
+
+	// CommonName is the client's common name, empty if the line does not name it
+	CommonName string `json:"commonName,omitempty"`
+
+	// RealAddress is the client's address as written in the log, empty if
+	// the line does not name it
+	RealAddress string `json:"realAddress,omitempty"`
+
+	// RealEndpoint is RealAddress parsed, nil if it could not be parsed
+	RealEndpoint *parser.Endpoint `json:"realEndpoint,omitempty"`
+
+	// Reason is the disconnect reason (e.g. "remote-exit"), the timeout
+	// option (e.g. "ping-restart") or the TLS error text
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the log message without timestamp and peer
+	Message string `json:"message"`
+
+	// Line is the line number (1-indexed)
+	Line int `json:"line"`
+}
+
+// Options controls how a log file is parsed.
+type Options struct {
+	// Location is the time zone of the log timestamps, time.Local if nil
+	Location *time.Location
+}
+
+// ParseStream reads log lines from r and calls fn for every event.
+// Parsing stops at the first error returned by fn or the reader.
+func ParseStream(r io.Reader, opts Options, fn func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		event, ok := parseLine(scanner.Text(), opts.Location)
+		if !ok {
+			continue
+		}
+		event.Line = lineNum
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading log: %w", err)
+	}
+	return nil
+}
+
+// Parse reads log lines from r and returns all events.
+func Parse(r io.Reader, opts Options) ([]Event, error) {
+	var events []Event
+	err := ParseStream(r, opts, func(event Event) error {
+		events = append(events, event)
+		return nil
+	})
+	return events, err
+}
+
+// ParseFile reads the log file at path and counts its events, see Summarize.
+func ParseFile(path string, opts Options) (*parser.LogStats, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	stats := &parser.LogStats{}
+	err = ParseStream(file, opts, func(event Event) error {
+		addEvent(stats, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Summarize counts events by type.
+func Summarize(events []Event) *parser.LogStats {
+	stats := &parser.LogStats{}
+	for _, event := range events {
+		addEvent(stats, event)
+	}
+	return stats
+}
+
+// addEvent adds event to the counters in stats
+func addEvent(stats *parser.LogStats, event Event) {
+	switch event.Type {
+	case EventConnected:
+		stats.Connections++
+	case EventAuthFailed:
+		if stats.AuthFailures == nil {
+			stats.AuthFailures = make(map[string]int64)
+		}
+		stats.AuthFailures[event.CommonName]++
+	case EventTLSError:
+		stats.TLSErrors++
+	case EventInactivityTimeout:
+		stats.InactivityTimeouts++
+	case EventDisconnected:
+		if stats.Disconnects == nil {
+			stats.Disconnects = make(map[string]int64)
+		}
+		stats.Disconnects[event.Reason]++
+	}
+}
+
+// parseLine recognizes a log line, ok is false if it is not an event.
+func parseLine(line string, loc *time.Location) (event Event, ok bool) {
+	rest := strings.TrimSpace(line)
+
+	// Timestamp: ISO 8601 since OpenVPN 2.5, ctime-style before
+	for _, n := range []int{len("2006-01-02 15:04:05"), len("Mon Jan _2 15:04:05 2006")} {
+		if len(rest) <= n || rest[n] != ' ' {
+			continue
+		}
+		if t, err := parser.ParseTime(rest[:n], loc); err == nil {
+			event.Time = t.Unix()
+			rest = strings.TrimSpace(rest[n:])
+			break
+		}
+	}
+
+	// Peer: "cn/address" or "address"
+	if token, msg, found := strings.Cut(rest, " "); found {
+		if cn, addr, ep := parsePeer(token); ep != nil {
+			event.CommonName = cn
+			event.RealAddress = addr
+			event.RealEndpoint = ep
+			rest = msg
+		}
+	}
+	event.Message = rest
+
+	switch {
+	case strings.Contains(rest, "AUTH_FAILED"):
+		// Server: "SENT CONTROL [user1]: 'AUTH_FAILED' (status=1)",
+		// client: "AUTH: Received control message: AUTH_FAILED"
+		event.Type = EventAuthFailed
+		if cn := bracketed(rest, "SENT CONTROL "); cn != "" && cn != "UNDEF" {
+			event.CommonName = cn
+		}
+
+	case strings.HasPrefix(rest, "TLS Error: "):
+		event.Type = EventTLSError
+		event.Reason = strings.TrimPrefix(rest, "TLS Error: ")
+
+	case strings.Contains(rest, "Peer Connection Initiated with "):
+		// "[user1] Peer Connection Initiated with [AF_INET]1.2.3.4:5678"
+		event.Type = EventConnected
+		if cn := bracketed(rest, ""); cn != "" {
+			event.CommonName = cn
+		}
+		if event.RealEndpoint == nil {
+			_, addr, _ := strings.Cut(rest, "Peer Connection Initiated with ")
+			if ep, err := parser.ParseEndpoint(addr); err == nil {
+				event.RealAddress = addr
+				event.RealEndpoint = &ep
+			}
+		}
+
+	case strings.Contains(rest, "Inactivity timeout ("):
+		// "[user1] Inactivity timeout (--ping-restart), restarting"
+		event.Type = EventInactivityTimeout
+		_, option, _ := strings.Cut(rest, "Inactivity timeout (")
+		option, _, _ = strings.Cut(option, ")")
+		event.Reason = strings.TrimPrefix(option, "--")
+
+	case strings.Contains(rest, "client-instance exiting"), strings.Contains(rest, "client-instance restarting"):
+		// "SIGTERM[soft,remote-exit] received, client-instance exiting"
+		event.Type = EventDisconnected
+		reason := bracketed(rest, "SIG")
+		if _, after, found := strings.Cut(reason, ","); found {
+			reason = after
+		}
+		event.Reason = reason
+
+	default:
+		return event, false
+	}
+
+	return event, true
+}
+
+// parsePeer splits the peer at the start of a log message into common name
+// and address. ep is nil if token is not a peer.
+func parsePeer(token string) (cn, addr string, ep *parser.Endpoint) {
+	addr = token
+	if i := strings.LastIndex(token, "/"); i >= 0 {
+		cn, addr = token[:i], token[i+1:]
+	}
+
+	endpoint, err := parser.ParseEndpoint(addr)
+	if err != nil || endpoint.Port == 0 {
+		return "", "", nil
+	}
+
+	// Not authenticated yet
+	if cn == "UNDEF" {
+		cn = ""
+	}
+	return cn, addr, &endpoint
+}
+
+// bracketed returns the text between the first "[" following prefix in s
+// and the next "]", or "" if there is none. An empty prefix matches the
+// start of s.
+func bracketed(s, prefix string) string {
+	i := 0
+	if prefix != "" {
+		i = strings.Index(s, prefix)
+		if i < 0 {
+			return ""
+		}
+		i += len(prefix)
+	}
+
+	start := strings.Index(s[i:], "[")
+	if start < 0 || (prefix == "" && start != 0) {
+		return ""
+	}
+	end := strings.Index(s[i+start:], "]")
+	if end < 0 {
+		return ""
+	}
+	return s[i+start+1 : i+start+end]
+}
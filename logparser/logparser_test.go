@@ -0,0 +1,139 @@
+package logparser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLog is a server log with one client connecting and leaving, one
+// failed login and one handshake that timed out
+const testLog = `2025-11-27 10:30:40 OpenVPN 2.6.8 x86_64-pc-linux-gnu [SSL (OpenSSL)] [LZO] [LZ4] [EPOLL]
+2025-11-27 10:30:45 192.168.1.100:54321 VERIFY OK: depth=0, CN=user1
+2025-11-27 10:30:45 192.168.1.100:54321 [user1] Peer Connection Initiated with [AF_INET]192.168.1.100:54321
+2025-11-27 10:30:45 user1/192.168.1.100:54321 MULTI_sva: pool returned IPv4=10.8.0.6, IPv6=(Not enabled)
+2025-11-27 10:31:10 203.0.113.7:40000 TLS Auth Error: Auth Username/Password verification failed for peer
+2025-11-27 10:31:10 203.0.113.7:40000 [mallory] Peer Connection Initiated with [AF_INET]203.0.113.7:40000
+2025-11-27 10:31:11 mallory/203.0.113.7:40000 SENT CONTROL [mallory]: 'AUTH_FAILED' (status=1)
+2025-11-27 10:32:00 198.51.100.9:1194 TLS Error: TLS key negotiation failed to occur within 60 seconds (check your network connectivity)
+2025-11-27 10:32:00 198.51.100.9:1194 TLS Error: TLS handshake failed
+2025-11-27 10:40:00 user1/192.168.1.100:54321 [user1] Inactivity timeout (--ping-restart), restarting
+2025-11-27 10:40:00 user1/192.168.1.100:54321 SIGUSR1[soft,ping-restart] received, client-instance restarting
+2025-11-27 10:45:00 user2/2001:db8::1:1194 SIGTERM[soft,remote-exit] received, client-instance exiting
+`
+
+// TestParse tests recognizing the event lines of a server log
+func TestParse(t *testing.T) {
+	events, err := Parse(strings.NewReader(testLog), Options{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []struct {
+		typ        EventType
+		commonName string
+		address    string
+		reason     string
+		line       int
+	}{
+		{EventConnected, "user1", "192.168.1.100:54321", "", 3},
+		{EventConnected, "mallory", "203.0.113.7:40000", "", 6},
+		{EventAuthFailed, "mallory", "203.0.113.7:40000", "", 7},
+		{EventTLSError, "", "198.51.100.9:1194", "TLS key negotiation failed to occur within 60 seconds (check your network connectivity)", 8},
+		{EventTLSError, "", "198.51.100.9:1194", "TLS handshake failed", 9},
+		{EventInactivityTimeout, "user1", "192.168.1.100:54321", "ping-restart", 10},
+		{EventDisconnected, "user1", "192.168.1.100:54321", "ping-restart", 11},
+		{EventDisconnected, "user2", "2001:db8::1:1194", "remote-exit", 12},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, exp := range expected {
+		event := events[i]
+		if event.Type != exp.typ || event.CommonName != exp.commonName || event.RealAddress != exp.address ||
+			event.Reason != exp.reason || event.Line != exp.line {
+			t.Errorf("Event %d: expected %+v, got %+v", i, exp, event)
+		}
+	}
+
+	if events[0].Time != 1764239445 {
+		t.Errorf("Expected Time 1764239445, got %d", events[0].Time)
+	}
+	if events[0].RealEndpoint == nil || events[0].RealEndpoint.Port != 54321 {
+		t.Errorf("Expected RealEndpoint with port 54321, got %+v", events[0].RealEndpoint)
+	}
+	if events[7].RealEndpoint == nil || events[7].RealEndpoint.Addr.String() != "2001:db8::1" {
+		t.Errorf("Expected RealEndpoint 2001:db8::1, got %+v", events[7].RealEndpoint)
+	}
+}
+
+// TestParseTimestampFormats tests logs with ctime-style and no timestamps
+func TestParseTimestampFormats(t *testing.T) {
+	tests := []struct {
+		line string
+		time int64
+	}{
+		{"Thu Nov 27 10:30:45 2025 user1/192.168.1.100:54321 SIGTERM[soft,remote-exit] received, client-instance exiting", 1764239445},
+		{"user1/192.168.1.100:54321 SIGTERM[soft,remote-exit] received, client-instance exiting", 0},
+	}
+
+	for _, tt := range tests {
+		events, err := Parse(strings.NewReader(tt.line), Options{Location: time.UTC})
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("Expected 1 event for '%s', got %d", tt.line, len(events))
+		}
+		if events[0].Time != tt.time || events[0].CommonName != "user1" {
+			t.Errorf("Expected user1 at %d, got %s at %d", tt.time, events[0].CommonName, events[0].Time)
+		}
+	}
+}
+
+// TestParseClientLog tests AUTH_FAILED as logged by the client
+func TestParseClientLog(t *testing.T) {
+	events, err := Parse(strings.NewReader("2025-11-27 10:30:45 AUTH: Received control message: AUTH_FAILED\n"), Options{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != EventAuthFailed || events[0].CommonName != "" {
+		t.Errorf("Expected one AUTH_FAILED event without common name, got %+v", events)
+	}
+}
+
+// TestParseFile tests counting the events of a log file
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openvpn.log")
+	if err := os.WriteFile(path, []byte(testLog), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	stats, err := ParseFile(path, Options{})
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	if stats.Connections != 2 {
+		t.Errorf("Expected 2 connections, got %d", stats.Connections)
+	}
+	if stats.AuthFailures["mallory"] != 1 || len(stats.AuthFailures) != 1 {
+		t.Errorf("Expected 1 auth failure for mallory, got %v", stats.AuthFailures)
+	}
+	if stats.TLSErrors != 2 {
+		t.Errorf("Expected 2 TLS errors, got %d", stats.TLSErrors)
+	}
+	if stats.InactivityTimeouts != 1 {
+		t.Errorf("Expected 1 inactivity timeout, got %d", stats.InactivityTimeouts)
+	}
+	if stats.Disconnects["ping-restart"] != 1 || stats.Disconnects["remote-exit"] != 1 {
+		t.Errorf("Expected 1 ping-restart and 1 remote-exit disconnect, got %v", stats.Disconnects)
+	}
+
+	if _, err := ParseFile(filepath.Join(t.TempDir(), "missing.log"), Options{}); err == nil {
+		t.Error("Expected error for missing log file")
+	}
+}
@@ -1,35 +1,90 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"openvpn-status-parser/ccd"
 	"openvpn-status-parser/config"
 	"openvpn-status-parser/formatter"
+	"openvpn-status-parser/ipp"
+	"openvpn-status-parser/logparser"
+	"openvpn-status-parser/management"
 	"openvpn-status-parser/parser"
+	"openvpn-status-parser/pki"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 const (
 	Version = "0.1.0"
+
+	// stdinPath as -file value reads a status file from stdin
+	stdinPath = "-"
+
+	// stdinServerID is the server ID used for status read from stdin
+	stdinServerID = "stdin"
+
+	// managementServerID is the server ID used for status read from the
+	// management interface without config file
+	managementServerID = "management"
 )
 
 func main() {
+	// Subcommands have their own flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "events":
+			os.Exit(runEvents(os.Args[2:]))
+		case "kill":
+			os.Exit(runKill(os.Args[2:]))
+		}
+	}
+
 	// Define command-line flags
-	filePath := flag.String("file", "", "Path to OpenVPN config file (required)")
+	filePath := flag.String("file", "", "Path to OpenVPN config file, or - to read a status file from stdin (required unless -management is set)")
+	managementAddr := flag.String("management", "", "Read the status from the management interface at host:port or a unix socket path instead of the status file")
+	managementPasswordFile := flag.String("management-password-file", "", "File with the management interface password (only with -management)")
 	format := flag.String("format", "json", "Output format: json or openmetrics")
 	indent := flag.Bool("indent", false, "Pretty-print JSON output (only for json format)")
+	includeUnknown := flag.Bool("include-unknown", false, "Add records of unknown type to the output (only for json format)")
+	endpointLabels := flag.Bool("endpoint-labels", false, "Add real_port and transport labels (only for openmetrics format)")
+	stream := flag.Bool("stream", false, "Write output while parsing instead of loading all clients into memory (OpenMetrics keeps a compact record per client)")
+	strict := flag.Bool("strict", false, "Check status file consistency and refuse to output incomplete snapshots")
+	retries := flag.Int("retries", 0, "Re-read a status file caught while OpenVPN rewrites it up to this many times")
+	timezone := flag.String("timezone", "", "Time zone of the times in the status file, e.g. Europe/Berlin (default: local time zone)")
+	retryBackoff := flag.Duration("retry-backoff", parser.DefaultRetryBackoff, "Delay before the first re-read, doubled for each further one")
+	readLog := flag.Bool("log", false, "Count logins, auth failures, TLS errors and disconnects in the log file of the config (log or log-append directive)")
+	logFile := flag.String("log-file", "", "Count logins, auth failures, TLS errors and disconnects in this OpenVPN log file")
+	readPool := flag.Bool("ipp", false, "Compare client addresses with the file of the ifconfig-pool-persist directive of the config")
+	poolFile := flag.String("ipp-file", "", "Compare client addresses with this ifconfig-pool-persist file")
+	certIndex := flag.String("cert-index", "", "Add certificate expiry and revocation of clients from this easy-rsa/OpenSSL index.txt")
+	certDir := flag.String("cert-dir", "", "Add certificate expiry of clients from the PEM certificates in this directory, e.g. easy-rsa pki/issued")
+	readCRL := flag.Bool("crl", false, "Check clients against the CRL of the crl-verify directive of the config (requires -cert-index or -cert-dir)")
+	crlFile := flag.String("crl-file", "", "Check clients against this CRL file or crl-verify directory (requires -cert-index or -cert-dir)")
+	configCwd := flag.String("config-cwd", "", "Working directory of OpenVPN for relative paths in the config file (default: directory of the config file)")
+	readCCD := flag.Bool("ccd", false, "Check clients and routes against the client-config-dir of the config")
+	ccdDir := flag.String("ccd-dir", "", "Check clients and routes against this client-config-dir")
 	version := flag.Bool("version", false, "Show version information")
 
 	// Custom usage message
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "OpenVPN Status Parser - Converts OpenVPN status files to JSON or OpenMetrics format\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s events [options]  (client events from the management interface)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s kill [options]    (disconnect clients through the management interface)\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s -file /etc/openvpn/server.conf\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -file /etc/openvpn/server.conf -format openmetrics\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -file - < /var/log/openvpn/status.log\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -management /run/openvpn/server.sock -format openmetrics\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -file /etc/openvpn/server.conf -format openmetrics -log\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -40,13 +95,69 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Validate required file flag
-	if *filePath == "" {
+	// Validate required file flag, the management interface replaces the status file
+	if *filePath == "" && *managementAddr == "" {
 		fmt.Fprintf(os.Stderr, "Error: -file flag is required\n\n")
 		flag.Usage()
 		os.Exit(1)
 	}
 
+	if *managementAddr != "" && *filePath == stdinPath {
+		fmt.Fprintf(os.Stderr, "Error: -management cannot be combined with -file -\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Strict mode decides about publishing after parsing, streaming has
+	// written the output by then
+	if *strict && *stream {
+		fmt.Fprintf(os.Stderr, "Error: -strict cannot be combined with -stream\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// A streamed snapshot cannot be re-read once it has been written out,
+	// the management interface is never caught mid-rewrite
+	if *retries > 0 && (*stream || *filePath == stdinPath || *managementAddr != "") {
+		fmt.Fprintf(os.Stderr, "Error: -retries cannot be combined with -stream, -management or -file -\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// The log and pool files are named in the config
+	if *readLog && (*filePath == "" || *filePath == stdinPath) {
+		fmt.Fprintf(os.Stderr, "Error: -log requires a config file, use -log-file instead\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *readPool && (*filePath == "" || *filePath == stdinPath) {
+		fmt.Fprintf(os.Stderr, "Error: -ipp requires a config file, use -ipp-file instead\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *configCwd != "" && (*filePath == "" || *filePath == stdinPath) {
+		fmt.Fprintf(os.Stderr, "Error: -config-cwd requires a config file\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *readCCD && (*filePath == "" || *filePath == stdinPath) {
+		fmt.Fprintf(os.Stderr, "Error: -ccd requires a config file, use -ccd-dir instead\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *readCRL && (*filePath == "" || *filePath == stdinPath) {
+		fmt.Fprintf(os.Stderr, "Error: -crl requires a config file, use -crl-file instead\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// The CRL lists serial numbers, clients are matched to them by common name
+	if (*readCRL || *crlFile != "") && *certIndex == "" && *certDir == "" {
+		fmt.Fprintf(os.Stderr, "Error: -crl and -crl-file require -cert-index or -cert-dir\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	// Validate format flag
 	if *format != "json" && *format != "openmetrics" {
 		fmt.Fprintf(os.Stderr, "Error: -format must be 'json' or 'openmetrics'\n\n")
@@ -54,69 +165,300 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Determine if input is a config file or status file
 	var statusFilePath string
-	var statusVer parser.StatusVersion
 	var serverConfig *parser.ServerConfig
+	var cfg *config.ServerConfig
 
-	// Parse OpenVPN config file
-	cfg, err := config.ParseConfig(*filePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to parse config file: %v\n", err)
-		os.Exit(1)
+	if *filePath == stdinPath {
+		// Status file piped on stdin, there is no config to take metadata from
+		statusFilePath = stdinPath
+		serverConfig = &parser.ServerConfig{ID: stdinServerID}
+	} else if *filePath == "" {
+		// Management interface only, there is no config to take metadata from
+		serverConfig = &parser.ServerConfig{ID: managementServerID}
+	} else {
+		// Parse OpenVPN config file
+		var err error
+		cfg, err = config.ParseConfigWithOptions(*filePath, config.ParseOptions{WorkingDir: *configCwd})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to parse config file: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Extract status file path from config
+		statusFilePath = cfg.StatusFile
+
+		// Convert config.ServerConfig to parser.ServerConfig
+		serverConfig = &parser.ServerConfig{
+			ID:    cfg.ID,
+			Local: cfg.Local,
+			Port:  cfg.Port,
+			Proto: cfg.Proto,
+			Dev:   cfg.Dev,
+		}
+
+		fmt.Fprintf(os.Stderr, "Config file parsed: server_id=%s, status=%s, version=%d\n",
+			serverConfig.ID, statusFilePath, cfg.StatusVersion)
 	}
 
-	// Extract status file path and version from config
-	statusFilePath = cfg.StatusFile
-	statusVer = getStatusVersion(cfg.StatusVersion)
+	// OpenVPN writes human-readable times in the server's time zone
+	location := time.Local
+	if *timezone != "" {
+		var err error
+		location, err = time.LoadLocation(*timezone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -timezone: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	// Convert config.ServerConfig to parser.ServerConfig
-	serverConfig = &parser.ServerConfig{
-		ID:    cfg.ID,
-		Local: cfg.Local,
-		Port:  cfg.Port,
-		Proto: cfg.Proto,
-		Dev:   cfg.Dev,
+	// The version is detected from the status file contents; a file that
+	// does not match the config is reported after parsing (exit 6)
+	opts := parser.ParseOptions{
+		Version:      parser.VersionAuto,
+		Strict:       *strict,
+		Retries:      *retries,
+		RetryBackoff: *retryBackoff,
+		Location:     location,
+	}
+
+	// Connect before any output is written, so that a failed login is a plain error
+	var mgmt *management.Client
+	if *managementAddr != "" {
+		var err error
+		mgmt, err = dialManagement(*managementAddr, *managementPasswordFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Server-level state is optional, the client list is still useful without it
+		info, err := mgmt.Info()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to read server info from management interface: %v\n", err)
+		} else {
+			serverConfig.Management = info
+		}
+	}
+
+	// Lifecycle counters are optional, the client list is still useful without them
+	if *readLog && *logFile == "" {
+		*logFile = cfg.LogFile
+		if *logFile == "" {
+			fmt.Fprintf(os.Stderr, "Warning: no 'log' or 'log-append' directive found in config file, OpenVPN may log to syslog\n")
+		}
+	}
+	if *logFile != "" {
+		stats, err := logparser.ParseFile(*logFile, logparser.Options{Location: location})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to read log file: %v\n", err)
+		} else {
+			serverConfig.Log = stats
+		}
 	}
 
-	fmt.Fprintf(os.Stderr, "Config file parsed: server_id=%s, status=%s, version=%d\n",
-		serverConfig.ID, statusFilePath, cfg.StatusVersion)
+	// Clients and routes are annotated from the other files as they are parsed
+	var ann annotations
+	if *readPool && *poolFile == "" {
+		*poolFile = cfg.PoolFile
+		if *poolFile == "" {
+			fmt.Fprintf(os.Stderr, "Warning: no 'ifconfig-pool-persist' directive found in config file\n")
+		}
+	}
+	if *poolFile != "" {
+		pool, poolErrors := ipp.ParseFile(*poolFile)
+		for _, err := range expandErrors(poolErrors) {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", *poolFile, err)
+		}
+		if pool != nil {
+			serverConfig.Pool = pool
+			ann.clients = append(ann.clients, ipp.NewAnnotator(pool).Annotate)
+			warnPoolConflicts(pool.Conflicts)
+		}
+	}
+	if *certIndex != "" || *certDir != "" {
+		inv := pki.NewInventory()
+		if *certIndex != "" {
+			for _, err := range expandErrors(inv.ReadIndex(*certIndex)) {
+				fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", *certIndex, err)
+			}
+		}
+		if *certDir != "" {
+			for _, err := range inv.ReadCertDir(*certDir) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
+		if *readCRL && *crlFile == "" {
+			*crlFile = cfg.CRLFile
+			if *crlFile == "" {
+				fmt.Fprintf(os.Stderr, "Warning: no 'crl-verify' directive found in config file\n")
+			}
+		}
+		var checker *pki.CRLChecker
+		if *crlFile != "" {
+			crl, err := pki.ReadCRL(*crlFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			} else {
+				checker = pki.NewCRLChecker(inv, crl, time.Now())
+				serverConfig.CRL = checker.Info()
+				warnCRL(*crlFile, checker.Info())
+			}
+		}
+
+		ann.clients = append(ann.clients, inv.Annotate)
+		if checker != nil {
+			ann.clients = append(ann.clients, checker.AnnotateClient)
+			ann.done = append(ann.done, func() {
+				warnRevokedSessions(checker.Sessions())
+			})
+		}
+	}
+	if *readCCD && *ccdDir == "" {
+		*ccdDir = cfg.ClientConfigDir
+		if *ccdDir == "" {
+			fmt.Fprintf(os.Stderr, "Warning: no 'client-config-dir' directive found in config file\n")
+		}
+	}
+	if *ccdDir != "" {
+		dir, ccdErrors := ccd.ReadDir(*ccdDir)
+		for _, err := range ccdErrors {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if dir != nil {
+			checker := ccd.NewChecker(dir)
+			serverConfig.CCD = checker.Info()
+			ann.clients = append(ann.clients, checker.AnnotateClient, warnStaticMismatch)
+			ann.routes = append(ann.routes, checker.AnnotateRoute)
+			ann.done = append(ann.done, checker.Finish, func() {
+				warnMissingIRoutes(checker.Info().MissingIRoutes)
+			})
+		}
+	}
 
-	// Parse the status file
-	status, parseErrors := parser.ParseFile(statusFilePath, statusVer)
+	var status *parser.Status
+	var parseErrors []error
+	var output string
+
+	if *stream {
+		// Write output while parsing, without holding all clients in memory
+		out := bufio.NewWriter(os.Stdout)
+		var sf formatter.StreamFormatter
+		switch *format {
+		case "json":
+			js := formatter.NewJSONStreamFormatter(out, *indent)
+			js.IncludeUnknown = *includeUnknown
+			sf = js
+		case "openmetrics":
+			om := formatter.NewOpenMetricsFormatter()
+			om.EndpointLabels = *endpointLabels
+			sf = om.NewStream(out, *serverConfig)
+		}
+
+		var err error
+		status, parseErrors, err = streamStatus(statusFilePath, mgmt, opts, serverConfig, sf, &ann)
+		if flushErr := out.Flush(); err == nil {
+			err = flushErr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write output: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		// Parse the status file
+		switch {
+		case mgmt != nil:
+			var err error
+			status, parseErrors, err = mgmt.Status(opts)
+			if err != nil {
+				parseErrors = []error{err}
+			}
+		case statusFilePath == stdinPath:
+			status, parseErrors = parser.Parse(os.Stdin, opts)
+		default:
+			status, parseErrors = parser.ParseFileWithOptions(statusFilePath, opts)
+		}
+
+		if status != nil {
+			ann.apply(status)
+		}
+	}
+
+	if mgmt != nil {
+		mgmt.Close()
+	}
+
+	// Warn if the file does not match an explicit status-version directive;
+	// client mode status files have a fixed format. The rows were parsed as
+	// the detected version, the mismatch only sets the exit code.
+	if status != nil && status.Statistics == nil && mgmt == nil && cfg != nil && cfg.StatusVersionSet &&
+		status.Version != parser.VersionAuto && status.Version != getStatusVersion(cfg.StatusVersion) {
+		fmt.Fprintf(os.Stderr, "Warning: config sets status-version %d but status file looks like version %d, parsing as version %d\n",
+			cfg.StatusVersion, status.Version, status.Version)
+		parseErrors = append(parseErrors, fmt.Errorf("%w: config sets status-version %d, status file looks like version %d",
+			parser.ErrVersionMismatch, cfg.StatusVersion, status.Version))
+	}
+
+	// Retries of a torn read are not errors, only the final outcome counts
+	retryNotices, parseErrors := parser.SplitRetryNotices(parseErrors)
+	for _, n := range retryNotices {
+		fmt.Fprintf(os.Stderr, "Note: %v\n", n)
+	}
 
 	// Report any parsing errors to stderr
 	if len(parseErrors) > 0 {
-		fmt.Fprintf(os.Stderr, "Warning: encountered %d error(s) during parsing:\n", len(parseErrors))
-		for _, err := range parseErrors {
+		reported := expandErrors(parseErrors)
+		fmt.Fprintf(os.Stderr, "Warning: encountered %d error(s) during parsing:\n", len(reported))
+		for _, err := range reported {
 			fmt.Fprintf(os.Stderr, "  %v\n", err)
 		}
+		printErrorSummary(parser.FlattenErrors(parseErrors))
 		fmt.Fprintf(os.Stderr, "\n")
 	}
 
 	// If status is nil, parsing failed completely
 	if status == nil {
 		fmt.Fprintf(os.Stderr, "Error: failed to parse status file\n")
+		if errors.Is(errors.Join(parseErrors...), parser.ErrFileNotFound) {
+			os.Exit(3)
+		}
 		os.Exit(1)
 	}
 
-	// Attach server config to status
-	status.Server = serverConfig
+	// Warn once per record type this version does not understand
+	warnUnknownRecords(status.Unknown)
 
-	// Select formatter based on format flag
-	var f formatter.Formatter
-	switch *format {
-	case "json":
-		f = formatter.NewJSONFormatter(*indent)
-	case "openmetrics":
-		f = formatter.NewOpenMetricsFormatter()
+	// Do not publish a snapshot OpenVPN was still writing
+	if *strict && !status.Complete {
+		fmt.Fprintf(os.Stderr, "Error: status file is incomplete (no END marker), refusing to output it\n")
+		os.Exit(7)
 	}
 
-	// Format the output
-	output, err := f.Format(status)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to format output: %v\n", err)
-		os.Exit(1)
+	if !*stream {
+		// Attach server config to status
+		status.Server = serverConfig
+
+		// Select formatter based on format flag
+		var f formatter.Formatter
+		switch *format {
+		case "json":
+			jf := formatter.NewJSONFormatter(*indent)
+			jf.IncludeUnknown = *includeUnknown
+			f = jf
+		case "openmetrics":
+			om := formatter.NewOpenMetricsFormatter()
+			om.EndpointLabels = *endpointLabels
+			f = om
+		}
+
+		// Format the output
+		var err error
+		output, err = f.Format(status)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to format output: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	// Write output to stdout
@@ -124,8 +466,180 @@ func main() {
 
 	// Exit with non-zero code if there were parse errors
 	if len(parseErrors) > 0 {
-		os.Exit(2)
+		os.Exit(parseErrorExitCode(parseErrors))
+	}
+}
+
+// parseErrorExitCode maps parse errors to the exit code of the most
+// severe error kind found:
+// 3 file not found, 7 truncated, 6 version mismatch, 4 short record,
+// 5 bad number, 2 any other parse error.
+func parseErrorExitCode(errs []error) int {
+	err := errors.Join(errs...)
+	switch {
+	case errors.Is(err, parser.ErrFileNotFound):
+		return 3
+	case errors.Is(err, parser.ErrTruncated):
+		return 7
+	case errors.Is(err, parser.ErrVersionMismatch):
+		return 6
+	case errors.Is(err, parser.ErrShortRecord):
+		return 4
+	case errors.Is(err, parser.ErrBadNumber):
+		return 5
+	default:
+		return 2
+	}
+}
+
+// streamStatus parses the status from mgmt if set, else the status file at
+// path (or stdin) with parser.ParseStream, handing rows to sf, and finishes
+// the output once parsing is done. Rows are annotated by ann first. The
+// returned error is an output error; parse and management errors are
+// returned in the error list as usual.
+func streamStatus(path string, mgmt *management.Client, opts parser.ParseOptions, server *parser.ServerConfig, sf formatter.StreamFormatter, ann *annotations) (*parser.Status, []error, error) {
+	h := ann.handler(sf)
+
+	var status *parser.Status
+	var parseErrors []error
+	if mgmt != nil {
+		var err error
+		status, parseErrors, err = mgmt.StatusStream(opts, h)
+		if err != nil {
+			parseErrors = []error{err}
+		}
+	} else if path == stdinPath {
+		status, parseErrors = parser.ParseStream(os.Stdin, opts, h)
+	} else {
+		status, parseErrors = parser.ParseFileStream(path, opts, h)
+	}
+	if status == nil {
+		return nil, parseErrors, nil
 	}
+
+	// Attach server config to status
+	status.Server = server
+	ann.finish()
+
+	return status, parseErrors, sf.Finish(status)
+}
+
+// expandErrors replaces every per-line ParseErrors group in errs by its
+// individual errors, so that each is reported on its own line.
+func expandErrors(errs []error) []error {
+	expanded := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if group, ok := err.(parser.ParseErrors); ok {
+			for _, e := range group {
+				expanded = append(expanded, e)
+			}
+			continue
+		}
+		expanded = append(expanded, err)
+	}
+	return expanded
+}
+
+// printErrorSummary prints the number of parse errors per field to stderr,
+// if there are errors for more than one field.
+func printErrorSummary(errs parser.ParseErrors) {
+	counts := errs.Summary()
+	if len(counts) < 2 {
+		return
+	}
+
+	fields := make([]string, 0, len(counts))
+	for field := range counts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	fmt.Fprintf(os.Stderr, "Errors by field:\n")
+	for _, field := range fields {
+		fmt.Fprintf(os.Stderr, "  %s: %d\n", field, counts[field])
+	}
+}
+
+// warnUnknownRecords prints a warning to stderr for each distinct unknown
+// record type, with the number of lines of that type.
+func warnUnknownRecords(records []parser.UnknownRecord) {
+	counts := make(map[string]int)
+	var types []string
+	for _, rec := range records {
+		if counts[rec.Type] == 0 {
+			types = append(types, rec.Type)
+		}
+		counts[rec.Type]++
+	}
+	for _, t := range types {
+		fmt.Fprintf(os.Stderr, "Warning: unknown record type %q on %d line(s), ignored by this version\n",
+			t, counts[t])
+	}
+}
+
+// warnPoolConflicts prints a warning to stderr for each address persisted
+// for more than one common name.
+func warnPoolConflicts(conflicts []parser.PoolConflict) {
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "Warning: address %s is persisted for several clients: %s\n",
+			c.Address, strings.Join(c.CommonNames, ", "))
+	}
+}
+
+// warnCRL prints a warning to stderr if the CRL is past its next update
+// or lacks revocations recorded in index.txt.
+func warnCRL(path string, crl *parser.CRLInfo) {
+	if crl.Stale {
+		fmt.Fprintf(os.Stderr, "Warning: CRL %s is stale, its next update was due %s\n",
+			path, time.Unix(crl.NextUpdate, 0).Format(time.RFC3339))
+	}
+	if crl.MissingRevocations > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: CRL %s lacks %d certificate(s) revoked in index.txt, regenerate it (easyrsa gen-crl)\n",
+			path, crl.MissingRevocations)
+	}
+}
+
+// warnStaticMismatch prints a warning to stderr if client is not on the
+// static address of its client-config-dir file.
+func warnStaticMismatch(client *parser.Client) {
+	if client.StaticMismatch {
+		fmt.Fprintf(os.Stderr, "Warning: client %s has address %s, client-config-dir pushes %s\n",
+			client.CommonName, client.VirtualAddress, client.StaticAddress)
+	}
+}
+
+// warnRevokedSessions prints a warning to stderr for each client that may
+// be connected with a revoked certificate.
+func warnRevokedSessions(sessions []pki.RevokedSession) {
+	for _, session := range sessions {
+		fmt.Fprintf(os.Stderr, "Warning: client %s (%s) may be connected with revoked certificate %s\n",
+			session.CommonName, session.RealAddress, session.Serial)
+	}
+}
+
+// warnMissingIRoutes prints a warning to stderr for each iroute of a
+// connected client that is not in the routing table.
+func warnMissingIRoutes(missing []parser.MissingIRoute) {
+	for _, m := range missing {
+		fmt.Fprintf(os.Stderr, "Warning: iroute %s of client %s is not in the routing table\n",
+			m.Network, m.CommonName)
+	}
+}
+
+// dialManagement connects to the management interface at addr, logging in
+// with the password from passwordFile if set.
+func dialManagement(addr, passwordFile string) (*management.Client, error) {
+	var opts management.Options
+	if passwordFile != "" {
+		password, err := management.ReadPasswordFile(passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.Password = password
+	}
+
+	network, address := management.ParseAddress(addr)
+	return management.Dial(network, address, opts)
 }
 
 // getStatusVersion converts an integer to StatusVersion type
@@ -0,0 +1,330 @@
+package management
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"openvpn-status-parser/parser"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType is the kind of a client notification
+type EventType string
+
+const (
+	// EventConnect - A client is authenticating (>CLIENT:CONNECT,
+	// only with management-client-auth)
+	EventConnect EventType = "CONNECT"
+
+	// EventReauth - A client renegotiates its session key (>CLIENT:REAUTH,
+	// only with management-client-auth)
+	EventReauth EventType = "REAUTH"
+
+	// EventEstablished - A client has connected (>CLIENT:ESTABLISHED)
+	EventEstablished EventType = "ESTABLISHED"
+
+	// EventDisconnect - A client has disconnected (>CLIENT:DISCONNECT)
+	EventDisconnect EventType = "DISCONNECT"
+
+	// EventByteCount - Periodic byte counters of a client (>BYTECOUNT_CLI)
+	EventByteCount EventType = "BYTECOUNT"
+)
+
+// Event is a real-time client notification from the management interface.
+type Event struct {
+	// Type is the kind of notification
+	Type EventType `json:"type"`
+
+	// Time is when the notification was received
+	Time time.Time `json:"time"`
+
+	// KeyID is the TLS key ID of CONNECT and REAUTH events
+	KeyID int64 `json:"keyId,omitempty"`
+
+	// Client holds the client's fields, filled from the ENV block and from
+	// earlier events of the same client ID. BYTECOUNT events of clients
+	// not seen before only have ClientID and the byte counters.
+	Client parser.Client `json:"client"`
+
+	// Env contains the ENV block sent with the notification, nil for BYTECOUNT
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// EventOptions controls what Events subscribes to.
+type EventOptions struct {
+	// ByteCountInterval enables BYTECOUNT events every interval seconds,
+	// 0 leaves the setting of the management interface unchanged
+	ByteCountInterval int
+
+	// SkipStatus does not read the current status first. By default the
+	// connected clients are loaded, so that their BYTECOUNT and DISCONNECT
+	// events carry all fields.
+	SkipStatus bool
+}
+
+// Subscription delivers events until its context is done or the
+// connection fails.
+type Subscription struct {
+	// Events receives the events and is closed when the subscription ends
+	Events <-chan Event
+
+	err error
+}
+
+// Err returns the error that ended the subscription, nil if it ended
+// because the context was done. Only valid once Events is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Events subscribes to client notifications. The Client must not be used
+// for anything else until the subscription has ended; cancel ctx to end it.
+func (c *Client) Events(ctx context.Context, opts EventOptions) (*Subscription, error) {
+	clients := make(map[int64]parser.Client)
+	if !opts.SkipStatus {
+		status, _, err := c.Status(parser.ParseOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, client := range status.ClientList {
+			clients[client.ClientID] = client
+		}
+	}
+
+	if opts.ByteCountInterval > 0 {
+		if _, err := c.command(fmt.Sprintf("bytecount %d", opts.ByteCountInterval)); err != nil {
+			return nil, err
+		}
+	}
+
+	events := make(chan Event)
+	sub := &Subscription{Events: events}
+
+	// Notifications arrive at any time, only ctx ends the wait
+	c.conn.SetDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetReadDeadline(time.Now())
+	})
+
+	go func() {
+		defer close(events)
+		defer stop()
+
+		r := &eventReader{c: c, clients: clients}
+		for {
+			event, err := r.next()
+			if err != nil {
+				if ctx.Err() == nil {
+					sub.err = err
+				}
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+// command sends a single-line command and returns the text of the
+// SUCCESS reply; ERROR replies are returned as error.
+func (c *Client) command(cmd string) (string, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := fmt.Fprintf(c.conn, "%s\n", cmd); err != nil {
+		return "", fmt.Errorf("failed to send %s command: %w", cmd, err)
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return "", fmt.Errorf("failed to read management response: %w", err)
+	}
+	switch {
+	case strings.HasPrefix(line, "SUCCESS:"):
+		return strings.TrimSpace(strings.TrimPrefix(line, "SUCCESS:")), nil
+	case strings.HasPrefix(line, "ERROR:"):
+		return "", fmt.Errorf("management interface: %s", line)
+	default:
+		return "", fmt.Errorf("unexpected management response to %s: %q", cmd, line)
+	}
+}
+
+// eventReader turns notification lines into events.
+type eventReader struct {
+	c *Client
+
+	// clients holds the last known state of every connected client
+	clients map[int64]parser.Client
+}
+
+// next reads notifications until a complete event is found.
+// Other notifications (>INFO, >LOG, ...) are skipped.
+func (r *eventReader) next() (Event, error) {
+	for {
+		line, err := r.readRaw()
+		if err != nil {
+			return Event{}, err
+		}
+
+		switch {
+		case strings.HasPrefix(line, ">CLIENT:"):
+			event, ok, err := r.clientEvent(strings.TrimPrefix(line, ">CLIENT:"))
+			if err != nil || ok {
+				return event, err
+			}
+		case strings.HasPrefix(line, ">BYTECOUNT_CLI:"):
+			event, err := r.byteCountEvent(strings.TrimPrefix(line, ">BYTECOUNT_CLI:"))
+			if err == nil {
+				return event, nil
+			}
+			// A malformed counter line is not worth ending the subscription
+		}
+	}
+}
+
+// clientEvent parses a >CLIENT notification and its ENV block.
+// ok is false for notifications that are not turned into events.
+func (r *eventReader) clientEvent(data string) (event Event, ok bool, err error) {
+	fields := strings.Split(data, ",")
+	event.Type = EventType(fields[0])
+	event.Time = time.Now()
+
+	switch event.Type {
+	case EventConnect, EventReauth, EventEstablished, EventDisconnect:
+	default:
+		// >CLIENT:ADDRESS, >CLIENT:CR_RESPONSE and future notifications
+		return event, false, nil
+	}
+
+	if len(fields) < 2 {
+		return event, false, nil
+	}
+	clientID, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return event, false, nil
+	}
+	if len(fields) > 2 {
+		event.KeyID, _ = strconv.ParseInt(fields[2], 10, 64)
+	}
+
+	event.Env, err = r.readEnv()
+	if err != nil {
+		return event, false, err
+	}
+
+	client := r.clients[clientID]
+	client.ClientID = clientID
+	applyEnv(&client, event.Env)
+	event.Client = client
+
+	if event.Type == EventDisconnect {
+		delete(r.clients, clientID)
+	} else {
+		r.clients[clientID] = client
+	}
+	return event, true, nil
+}
+
+// readEnv reads ">CLIENT:ENV,name=value" lines up to ">CLIENT:ENV,END".
+func (r *eventReader) readEnv() (map[string]string, error) {
+	env := make(map[string]string)
+	for {
+		line, err := r.readRaw()
+		if err != nil {
+			return nil, err
+		}
+		entry, ok := strings.CutPrefix(line, ">CLIENT:ENV,")
+		if !ok {
+			// Not part of the ENV block, e.g. an interleaved >LOG line
+			continue
+		}
+		if entry == "END" {
+			return env, nil
+		}
+		name, value, _ := strings.Cut(entry, "=")
+		env[name] = value
+	}
+}
+
+// byteCountEvent parses ">BYTECOUNT_CLI:{CID},{BYTES_IN},{BYTES_OUT}".
+// Bytes in are received from the client, bytes out are sent to it.
+func (r *eventReader) byteCountEvent(data string) (Event, error) {
+	fields := strings.Split(data, ",")
+	if len(fields) != 3 {
+		return Event{}, fmt.Errorf("expected 3 fields, got %d", len(fields))
+	}
+
+	var values [3]int64
+	for i, field := range fields {
+		val, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return Event{}, err
+		}
+		values[i] = val
+	}
+
+	client := r.clients[values[0]]
+	client.ClientID = values[0]
+	client.BytesReceived = values[1]
+	client.BytesSent = values[2]
+	if _, ok := r.clients[client.ClientID]; ok {
+		r.clients[client.ClientID] = client
+	}
+
+	return Event{Type: EventByteCount, Time: time.Now(), Client: client}, nil
+}
+
+// readRaw reads the next line including notifications, without line ending.
+func (r *eventReader) readRaw() (string, error) {
+	line, err := r.c.r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read management notification: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// applyEnv sets the client fields found in a notification's ENV block,
+// leaving fields that are not present untouched.
+func applyEnv(client *parser.Client, env map[string]string) {
+	setString := func(dst *string, name string) {
+		if value, ok := env[name]; ok {
+			*dst = value
+		}
+	}
+	setInt := func(dst *int64, name string) {
+		if value, err := strconv.ParseInt(env[name], 10, 64); err == nil {
+			*dst = value
+		}
+	}
+
+	setString(&client.CommonName, "common_name")
+	setString(&client.Username, "username")
+	setString(&client.VirtualAddress, "ifconfig_pool_remote_ip")
+	setString(&client.VirtualIPv6Address, "ifconfig_pool_remote_ip6")
+	setString(&client.ConnectedSince, "time_ascii")
+	setInt(&client.ConnectedSinceTime, "time_unix")
+	setInt(&client.BytesReceived, "bytes_received")
+	setInt(&client.BytesSent, "bytes_sent")
+
+	// trusted_* is set once the client is authenticated, untrusted_* before
+	for _, prefix := range []string{"trusted_", "untrusted_"} {
+		ip := env[prefix+"ip"]
+		if ip == "" {
+			ip = env[prefix+"ip6"]
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+		port, _ := strconv.ParseUint(env[prefix+"port"], 10, 16)
+		ep := parser.Endpoint{Addr: addr, Port: uint16(port)}
+		client.RealAddress = ep.String()
+		client.RealEndpoint = &ep
+		break
+	}
+}
@@ -0,0 +1,127 @@
+package management
+
+import (
+	"bufio"
+	"fmt"
+	"openvpn-status-parser/parser"
+	"strconv"
+	"strings"
+)
+
+// Info runs load-stats, state and version and collects their results.
+func (c *Client) Info() (*parser.ManagementInfo, error) {
+	info := &parser.ManagementInfo{}
+
+	stats, err := c.command("load-stats")
+	if err != nil {
+		return nil, err
+	}
+	if err := parseLoadStats(stats, info); err != nil {
+		return nil, err
+	}
+
+	state, err := c.lines("state")
+	if err != nil {
+		return nil, err
+	}
+	if err := parseState(state, info); err != nil {
+		return nil, err
+	}
+
+	version, err := c.lines("version")
+	if err != nil {
+		return nil, err
+	}
+	parseVersion(version, info)
+
+	return info, nil
+}
+
+// lines runs a multi-line command and returns the response lines without END.
+func (c *Client) lines(cmd string) ([]string, error) {
+	r, err := c.multiLine(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "END" {
+			lines = append(lines, line)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return lines, scanner.Err()
+}
+
+// parseLoadStats parses the load-stats response.
+// Format: nclients=<n>,bytesin=<n>,bytesout=<n>
+func parseLoadStats(s string, info *parser.ManagementInfo) error {
+	for _, pair := range strings.Split(s, ",") {
+		name, value, _ := strings.Cut(pair, "=")
+
+		var dst *int64
+		switch name {
+		case "nclients":
+			dst = &info.Clients
+		case "bytesin":
+			dst = &info.BytesIn
+		case "bytesout":
+			dst = &info.BytesOut
+		default:
+			continue
+		}
+
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid load-stats value %s: %w", pair, err)
+		}
+		*dst = val
+	}
+	return nil
+}
+
+// parseState parses the state response; the last line is the current state.
+// Format: <time>,<state>,<description>,<local ip>,<remote ip>,...
+func parseState(lines []string, info *parser.ManagementInfo) error {
+	if len(lines) == 0 {
+		return fmt.Errorf("empty state response")
+	}
+
+	fields := strings.Split(lines[len(lines)-1], ",")
+	if len(fields) < 2 {
+		return fmt.Errorf("invalid state line %q", lines[len(lines)-1])
+	}
+
+	t, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid state time %q: %w", fields[0], err)
+	}
+	info.StateTime = t
+	info.State = fields[1]
+	if len(fields) > 3 {
+		info.LocalIP = fields[3]
+	}
+	return nil
+}
+
+// parseVersion parses the version response.
+// Format: "OpenVPN Version: <version string>", "Management Version: <n>"
+func parseVersion(lines []string, info *parser.ManagementInfo) {
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch name {
+		case "OpenVPN Version":
+			info.OpenVPNVersion = value
+		case "Management Version", "Management Interface Version":
+			info.ManagementVersion = value
+		}
+	}
+}
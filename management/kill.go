@@ -0,0 +1,251 @@
+package management
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"openvpn-status-parser/parser"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TargetKind selects how a Target identifies clients
+type TargetKind string
+
+const (
+	// TargetCommonName - All clients with the common name, "kill "<cn>""
+	TargetCommonName TargetKind = "commonName"
+
+	// TargetRealAddress - The client at the real address, "kill <ip:port>",
+	// IPv6 addresses in brackets
+	TargetRealAddress TargetKind = "realAddress"
+
+	// TargetClientID - The client with the client ID, "client-kill <id>"
+	TargetClientID TargetKind = "clientId"
+)
+
+// Target identifies the clients to disconnect.
+type Target struct {
+	// Kind is how Value identifies clients
+	Kind TargetKind `json:"kind"`
+
+	// Value is the common name, real address or client ID
+	Value string `json:"value"`
+}
+
+// String returns the target in "kind=value" form
+func (t Target) String() string {
+	return string(t.Kind) + "=" + t.Value
+}
+
+// DefaultVerifyTimeout is how long Kill waits for killed clients to
+// disappear from the status
+const DefaultVerifyTimeout = 5 * time.Second
+
+// verifyInterval is the delay between status reads while verifying a kill
+const verifyInterval = 200 * time.Millisecond
+
+var (
+	// ErrClientNotFound is returned by Kill if no connected client matches the target
+	ErrClientNotFound = errors.New("no connected client matches")
+
+	// ErrStillConnected is returned by Kill if a killed client is still in
+	// the status after the verify timeout
+	ErrStillConnected = errors.New("client still connected after kill")
+
+	// ErrInvalidTarget is returned by Kill for a target value that would
+	// end the management command early
+	ErrInvalidTarget = errors.New("invalid target")
+)
+
+// KillResult describes what Kill did.
+type KillResult struct {
+	// Command is the management command sent, empty if none was sent
+	Command string `json:"command,omitempty"`
+
+	// Response is the SUCCESS message of the management interface
+	Response string `json:"response,omitempty"`
+
+	// Killed are the clients that matched the target before the kill
+	Killed []parser.Client `json:"killed"`
+
+	// Verified is true once none of the killed clients is in the status
+	// anymore (a client reconnecting gets a new client ID)
+	Verified bool `json:"verified"`
+}
+
+// Kill disconnects the clients matching target and waits up to
+// verifyTimeout (DefaultVerifyTimeout if zero) for them to disappear from
+// the status. The result is returned together with the error if the kill
+// was sent but could not be verified.
+func (c *Client) Kill(target Target, verifyTimeout time.Duration) (*KillResult, error) {
+	if verifyTimeout <= 0 {
+		verifyTimeout = DefaultVerifyTimeout
+	}
+
+	// A line break would let the value send further commands
+	if strings.ContainsAny(target.Value, "\r\n\x00") {
+		return nil, fmt.Errorf("%w %q: contains a line break", ErrInvalidTarget, target.Value)
+	}
+
+	status, _, err := c.Status(parser.ParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &KillResult{Killed: matchTarget(status.ClientList, target)}
+	if len(result.Killed) == 0 {
+		return result, fmt.Errorf("%w %s", ErrClientNotFound, target)
+	}
+
+	switch target.Kind {
+	case TargetClientID:
+		result.Command = "client-kill " + target.Value
+	case TargetRealAddress:
+		// IPv6 addresses are bracketed, "kill [2001:db8::1]:443"
+		ep := result.Killed[0].RealEndpoint
+		result.Command = "kill " + netip.AddrPortFrom(ep.Addr, ep.Port).String()
+	default:
+		result.Command = "kill " + quoteParam(target.Value)
+	}
+
+	result.Response, err = c.command(result.Command)
+	if err != nil {
+		return result, err
+	}
+
+	deadline := time.Now().Add(verifyTimeout)
+	for {
+		status, _, err := c.Status(parser.ParseOptions{})
+		if err != nil {
+			return result, err
+		}
+		if !anyConnected(status.ClientList, result.Killed) {
+			result.Verified = true
+			return result, nil
+		}
+		if time.Now().After(deadline) {
+			return result, fmt.Errorf("%w: %s", ErrStillConnected, target)
+		}
+		time.Sleep(verifyInterval)
+	}
+}
+
+// matchTarget returns the clients matched by target.
+// Real addresses are compared by IP and port, ignoring the transport prefix.
+func matchTarget(clients []parser.Client, target Target) []parser.Client {
+	var targetEndpoint parser.Endpoint
+	if target.Kind == TargetRealAddress {
+		ep, err := parser.ParseEndpoint(target.Value)
+		if err != nil {
+			return nil
+		}
+		targetEndpoint = ep
+	}
+
+	var matched []parser.Client
+	for _, client := range clients {
+		var ok bool
+		switch target.Kind {
+		case TargetCommonName:
+			ok = client.CommonName == target.Value
+		case TargetClientID:
+			ok = strconv.FormatInt(client.ClientID, 10) == target.Value
+		case TargetRealAddress:
+			ep := client.RealEndpoint
+			ok = ep != nil && ep.Addr == targetEndpoint.Addr && ep.Port == targetEndpoint.Port
+		}
+		if ok {
+			matched = append(matched, client)
+		}
+	}
+	return matched
+}
+
+// anyConnected reports whether one of killed is still in clients
+func anyConnected(clients, killed []parser.Client) bool {
+	ids := make(map[int64]bool, len(killed))
+	for _, client := range killed {
+		ids[client.ClientID] = true
+	}
+	for _, client := range clients {
+		if ids[client.ClientID] {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditRecord is a line of the kill audit log.
+type AuditRecord struct {
+	// Time is when the kill finished
+	Time time.Time `json:"time"`
+
+	// Operator is who ran the kill, see Operator
+	Operator string `json:"operator"`
+
+	// Target is what was to be killed
+	Target Target `json:"target"`
+
+	// Result is what Kill did, nil if it failed before reading the status
+	Result *KillResult `json:"result,omitempty"`
+
+	// Error is the error returned by Kill, if any
+	Error string `json:"error,omitempty"`
+}
+
+// NewAuditRecord creates an audit record for a kill of target by the
+// current operator, see Operator.
+func NewAuditRecord(target Target, result *KillResult, err error) AuditRecord {
+	rec := AuditRecord{
+		Time:     time.Now().UTC(),
+		Operator: Operator(),
+		Target:   target,
+		Result:   result,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+// Operator returns the name of the person running the command: the user
+// who invoked sudo if set, else the current user.
+func Operator() string {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// AppendAudit appends rec as a JSON line to the audit log at path.
+func AppendAudit(path string, rec AuditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return file.Close()
+}
+
+// quoteParam quotes a management command parameter as OpenVPN's
+// parse_line reads it, so that common names with spaces, quotes or
+// backslashes stay a single parameter.
+func quoteParam(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
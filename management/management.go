@@ -0,0 +1,241 @@
+package management
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"openvpn-status-parser/parser"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout limits how long a single command may take
+const DefaultTimeout = 10 * time.Second
+
+// passwordPrompt is sent by OpenVPN, without newline, when the management
+// interface is protected with a password file
+const passwordPrompt = "ENTER PASSWORD:"
+
+// Options controls how Dial connects to the management interface.
+type Options struct {
+	// Password is sent when OpenVPN asks for it, empty if the interface
+	// has no password
+	Password string
+
+	// Timeout limits connecting and every command. DefaultTimeout is used if zero.
+	Timeout time.Duration
+}
+
+// Client is a connection to the OpenVPN management interface.
+// OpenVPN serves one management client at a time, so a Client should be
+// closed as soon as it is no longer needed.
+type Client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+}
+
+// Dial connects to the management interface at address. network is "tcp"
+// or "unix", see ParseAddress. If opts.Password is set, Dial waits for the
+// password prompt and logs in.
+func Dial(network, address string, opts Options) (*Client, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to management interface: %w", err)
+	}
+
+	c := &Client{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	if opts.Password != "" {
+		if err := c.login(opts.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// login answers the password prompt.
+// OpenVPN replies "SUCCESS: password is correct" or closes the connection.
+func (c *Client) login(password string) error {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	prompt, err := c.r.ReadString(':')
+	if err != nil {
+		return fmt.Errorf("failed to read password prompt: %w", err)
+	}
+	if strings.TrimSpace(prompt) != passwordPrompt {
+		return fmt.Errorf("expected password prompt, got %q", prompt)
+	}
+
+	if _, err := fmt.Fprintf(c.conn, "%s\n", password); err != nil {
+		return fmt.Errorf("failed to send password: %w", err)
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return fmt.Errorf("management interface rejected password: %w", err)
+	}
+	if !strings.HasPrefix(line, "SUCCESS:") {
+		return fmt.Errorf("management interface rejected password: %s", line)
+	}
+	return nil
+}
+
+// Close ends the management session and closes the connection.
+func (c *Client) Close() error {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	fmt.Fprintf(c.conn, "quit\n")
+	return c.conn.Close()
+}
+
+// Status runs "status 3" and parses the response with parser.Parse.
+// err is set if the command failed, e.g. the connection was lost; parse
+// errors are returned in the error list as for status files.
+func (c *Client) Status(opts parser.ParseOptions) (status *parser.Status, parseErrors []error, err error) {
+	r, err := c.statusReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts.Version = parser.Version3
+	status, parseErrors = parser.Parse(r, opts)
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	return status, parseErrors, nil
+}
+
+// StatusStream works like Status but hands clients and routes to h as they
+// arrive, see parser.ParseStream.
+func (c *Client) StatusStream(opts parser.ParseOptions, h parser.Handler) (status *parser.Status, parseErrors []error, err error) {
+	r, err := c.statusReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts.Version = parser.Version3
+	status, parseErrors = parser.ParseStream(r, opts, h)
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	return status, parseErrors, nil
+}
+
+// statusReader sends "status 3" and returns a reader for the response.
+func (c *Client) statusReader() (*responseReader, error) {
+	return c.multiLine("status 3")
+}
+
+// multiLine sends a command answered with several lines up to END and
+// returns a reader for the response.
+func (c *Client) multiLine(cmd string) (*responseReader, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := fmt.Fprintf(c.conn, "%s\n", cmd); err != nil {
+		return nil, fmt.Errorf("failed to send %s command: %w", cmd, err)
+	}
+	return &responseReader{c: c}, nil
+}
+
+// readLine reads the next line that is not a real-time notification
+// (">INFO:...", ">CLIENT:..." and so on), without line ending.
+func (c *Client) readLine() (string, error) {
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		return line, nil
+	}
+}
+
+// responseReader reads a multi-line command response up to and including
+// the END line, with "\n" line endings. Errors are kept in err, so that
+// they are not mistaken for parse errors.
+type responseReader struct {
+	c    *Client
+	buf  []byte
+	done bool
+	err  error
+}
+
+// Read implements io.Reader
+func (rr *responseReader) Read(p []byte) (int, error) {
+	for len(rr.buf) == 0 {
+		if rr.done || rr.err != nil {
+			return 0, io.EOF
+		}
+
+		line, err := rr.c.readLine()
+		if err != nil {
+			rr.err = fmt.Errorf("failed to read management response: %w", err)
+			return 0, io.EOF
+		}
+
+		switch {
+		case strings.HasPrefix(line, "ERROR:"):
+			rr.err = fmt.Errorf("management interface: %s", line)
+			return 0, io.EOF
+		case strings.HasPrefix(line, passwordPrompt):
+			rr.err = fmt.Errorf("management interface requires a password")
+			return 0, io.EOF
+		case line == "END":
+			rr.done = true
+		}
+
+		rr.buf = append(rr.buf[:0], line...)
+		rr.buf = append(rr.buf, '\n')
+	}
+
+	n := copy(p, rr.buf)
+	rr.buf = rr.buf[n:]
+	return n, nil
+}
+
+// ParseAddress splits a management address given on the command line into
+// network and address:
+//
+//	unix:///run/openvpn/server.sock  -> unix, /run/openvpn/server.sock
+//	/run/openvpn/server.sock         -> unix, /run/openvpn/server.sock
+//	tcp://127.0.0.1:7505             -> tcp, 127.0.0.1:7505
+//	127.0.0.1:7505                   -> tcp, 127.0.0.1:7505
+func ParseAddress(s string) (network, address string) {
+	switch {
+	case strings.HasPrefix(s, "unix://"):
+		return "unix", strings.TrimPrefix(s, "unix://")
+	case strings.HasPrefix(s, "tcp://"):
+		return "tcp", strings.TrimPrefix(s, "tcp://")
+	case strings.Contains(s, "/"):
+		return "unix", s
+	default:
+		return "tcp", s
+	}
+}
+
+// ReadPasswordFile reads the management password from the first line of
+// path, the same file format OpenVPN's management directive uses.
+func ReadPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	password, _, _ := strings.Cut(string(data), "\n")
+	password = strings.TrimRight(password, "\r")
+	if password == "" {
+		return "", fmt.Errorf("password file %s is empty", path)
+	}
+	return password, nil
+}
@@ -0,0 +1,546 @@
+package management
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"openvpn-status-parser/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statusResponse is a "status 3" response as sent by OpenVPN
+const statusResponse = "TITLE\tOpenVPN 2.6.8 x86_64-pc-linux-gnu\r\n" +
+	"TIME\tThu Nov 27 10:30:45 2025\t1732704645\r\n" +
+	"HEADER\tCLIENT_LIST\tCommon Name\tReal Address\tVirtual Address\tVirtual IPv6 Address\tBytes Received\tBytes Sent\tConnected Since\tConnected Since (time_t)\tUsername\tClient ID\tPeer ID\tData Channel Cipher\r\n" +
+	"CLIENT_LIST\tuser1\t192.168.1.100:54321\t10.8.0.2\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\t0\tAES-256-GCM\r\n" +
+	">BYTECOUNT_CLI:0,1048576,2097152\r\n" +
+	"ROUTING_TABLE\t10.8.0.2\tuser1\t192.168.1.100:54321\tThu Nov 27 10:30:45 2025\t1732704645\r\n" +
+	"GLOBAL_STATS\tMax bcast/mcast queue length\t0\r\n" +
+	"END\r\n"
+
+// fakeServer is a minimal management interface answering commands with
+// canned responses.
+type fakeServer struct {
+	listener  net.Listener
+	password  string
+	responses map[string][]string
+
+	// commands receives every command sent by the client
+	commands chan string
+}
+
+// startFakeServer listens on network and serves a single connection.
+// responses maps commands to the raw responses sent for them, one per
+// time the command is received; the last one is repeated.
+func startFakeServer(t *testing.T, network, password string, responses map[string][]string) *fakeServer {
+	t.Helper()
+
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		address = filepath.Join(t.TempDir(), "management.sock")
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeServer{listener: listener, password: password, responses: responses, commands: make(chan string, 10)}
+	go s.serve()
+	return s
+}
+
+// serve handles one client the way OpenVPN does
+func (s *fakeServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	if s.password != "" {
+		conn.Write([]byte(passwordPrompt))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.TrimSpace(line) != s.password {
+			conn.Write([]byte("ERROR: bad password\r\n"))
+			return
+		}
+		conn.Write([]byte("SUCCESS: password is correct\r\n"))
+	}
+	conn.Write([]byte(">INFO:OpenVPN Management Interface Version 5 -- type 'help' for more info\r\n"))
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		s.commands <- cmd
+		if cmd == "quit" {
+			return
+		}
+		queue, ok := s.responses[cmd]
+		if !ok {
+			conn.Write([]byte("ERROR: unknown command, enter 'help' for more options\r\n"))
+			continue
+		}
+		response := queue[0]
+		if len(queue) > 1 {
+			s.responses[cmd] = queue[1:]
+		}
+		conn.Write([]byte(response))
+		// A response without END, SUCCESS or ERROR simulates OpenVPN going away
+		if !strings.HasSuffix(response, "END\r\n") && !strings.HasPrefix(response, "SUCCESS:") && !strings.HasPrefix(response, "ERROR:") {
+			return
+		}
+	}
+}
+
+// TestStatus tests reading the status over TCP and Unix sockets
+func TestStatus(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		server := startFakeServer(t, network, "", map[string][]string{"status 3": {statusResponse}})
+
+		client, err := Dial(network, server.listener.Addr().String(), Options{Timeout: time.Second})
+		if err != nil {
+			t.Fatalf("%s: Dial failed: %v", network, err)
+		}
+
+		status, errs, err := client.Status(parser.ParseOptions{})
+		if err != nil {
+			t.Fatalf("%s: Status failed: %v", network, err)
+		}
+		if len(errs) > 0 {
+			t.Errorf("%s: expected no parse errors, got %v", network, errs)
+		}
+		if !status.Complete {
+			t.Errorf("%s: expected complete status", network)
+		}
+		if len(status.ClientList) != 1 || status.ClientList[0].CommonName != "user1" {
+			t.Errorf("%s: expected client user1, got %v", network, status.ClientList)
+		}
+		if len(status.RoutingTable) != 1 {
+			t.Errorf("%s: expected 1 route, got %d", network, len(status.RoutingTable))
+		}
+
+		if err := client.Close(); err != nil {
+			t.Errorf("%s: Close failed: %v", network, err)
+		}
+		if cmd := <-server.commands; cmd != "status 3" {
+			t.Errorf("%s: expected command 'status 3', got '%s'", network, cmd)
+		}
+	}
+}
+
+// TestStatusStream tests streaming clients from the management interface
+func TestStatusStream(t *testing.T) {
+	server := startFakeServer(t, "tcp", "", map[string][]string{"status 3": {statusResponse}})
+
+	client, err := Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	var clients []string
+	h := parser.HandlerFuncs{Client: func(c parser.Client) error {
+		clients = append(clients, c.CommonName)
+		return nil
+	}}
+	status, _, err := client.StatusStream(parser.ParseOptions{}, h)
+	if err != nil {
+		t.Fatalf("StatusStream failed: %v", err)
+	}
+	if len(clients) != 1 || !status.Complete {
+		t.Errorf("Expected 1 streamed client and complete status, got %v, complete=%v", clients, status.Complete)
+	}
+}
+
+// TestPassword tests logging in with a password
+func TestPassword(t *testing.T) {
+	server := startFakeServer(t, "tcp", "secret", map[string][]string{"status 3": {statusResponse}})
+	client, err := Dial("tcp", server.listener.Addr().String(), Options{Password: "secret", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial with password failed: %v", err)
+	}
+	defer client.Close()
+
+	if _, _, err := client.Status(parser.ParseOptions{}); err != nil {
+		t.Errorf("Status failed: %v", err)
+	}
+
+	server = startFakeServer(t, "tcp", "secret", map[string][]string{"status 3": {statusResponse}})
+	if _, err := Dial("tcp", server.listener.Addr().String(), Options{Password: "wrong", Timeout: time.Second}); err == nil {
+		t.Error("Expected error for wrong password")
+	}
+
+	server = startFakeServer(t, "tcp", "secret", map[string][]string{"status 3": {statusResponse}})
+	client, err = Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+	if _, _, err := client.Status(parser.ParseOptions{}); err == nil || !strings.Contains(err.Error(), "requires a password") {
+		t.Errorf("Expected password required error, got %v", err)
+	}
+}
+
+// TestStatusError tests that ERROR responses and lost connections are reported
+func TestStatusError(t *testing.T) {
+	server := startFakeServer(t, "tcp", "", map[string][]string{"status 3": {"ERROR: status command failed\r\n"}})
+	client, err := Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	status, _, err := client.Status(parser.ParseOptions{})
+	if err == nil || !strings.Contains(err.Error(), "status command failed") {
+		t.Errorf("Expected management error, got %v", err)
+	}
+	if status != nil {
+		t.Error("Expected nil status on error")
+	}
+
+	// Connection closed in the middle of the response
+	server = startFakeServer(t, "tcp", "", map[string][]string{"status 3": {"TITLE\tOpenVPN\r\n"}})
+	client, err = Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+	if _, _, err := client.Status(parser.ParseOptions{}); err == nil {
+		t.Error("Expected error for incomplete response")
+	}
+}
+
+// eventNotifications are sent by the fake server after "bytecount 5"
+const eventNotifications = "SUCCESS: bytecount interval changed\r\n" +
+	">CLIENT:CONNECT,1,2\r\n" +
+	">CLIENT:ENV,common_name=alice\r\n" +
+	">CLIENT:ENV,untrusted_ip=203.0.113.50\r\n" +
+	">CLIENT:ENV,untrusted_port=12345\r\n" +
+	">CLIENT:ENV,END\r\n" +
+	">LOG:1732696530,I,alice/203.0.113.50:12345 MULTI: primary virtual IP for alice/203.0.113.50:12345: 10.8.0.6\r\n" +
+	">CLIENT:ESTABLISHED,1\r\n" +
+	">CLIENT:ENV,common_name=alice\r\n" +
+	">CLIENT:ENV,username=alice\r\n" +
+	">CLIENT:ENV,trusted_ip=203.0.113.50\r\n" +
+	">CLIENT:ENV,trusted_port=12345\r\n" +
+	">CLIENT:ENV,ifconfig_pool_remote_ip=10.8.0.6\r\n" +
+	">CLIENT:ENV,time_unix=1732696530\r\n" +
+	">CLIENT:ENV,END\r\n" +
+	">CLIENT:ADDRESS,1,10.8.0.6,1\r\n" +
+	">BYTECOUNT_CLI:0,100,200\r\n" +
+	">CLIENT:DISCONNECT,1\r\n" +
+	">CLIENT:ENV,bytes_received=5000\r\n" +
+	">CLIENT:ENV,bytes_sent=6000\r\n" +
+	">CLIENT:ENV,END\r\n"
+
+// TestEvents tests parsing client notifications into events
+func TestEvents(t *testing.T) {
+	server := startFakeServer(t, "tcp", "", map[string][]string{
+		"status 3":    {statusResponse},
+		"bytecount 5": {eventNotifications},
+	})
+	client, err := Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := client.Events(ctx, EventOptions{ByteCountInterval: 5})
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+
+	var events []Event
+	for event := range sub.Events {
+		events = append(events, event)
+		if len(events) == 4 {
+			cancel()
+		}
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("Expected no error after cancel, got %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d: %v", len(events), events)
+	}
+
+	connect := events[0]
+	if connect.Type != EventConnect || connect.KeyID != 2 || connect.Client.ClientID != 1 {
+		t.Errorf("Expected CONNECT of client 1 with key 2, got %+v", connect)
+	}
+	if connect.Client.RealAddress != "203.0.113.50:12345" {
+		t.Errorf("Expected untrusted address on CONNECT, got '%s'", connect.Client.RealAddress)
+	}
+
+	established := events[1].Client
+	if events[1].Type != EventEstablished || established.CommonName != "alice" || established.VirtualAddress != "10.8.0.6" {
+		t.Errorf("Expected ESTABLISHED of alice at 10.8.0.6, got %+v", events[1])
+	}
+	if established.ConnectedSinceTime != 1732696530 || established.RealEndpoint == nil || established.RealEndpoint.Port != 12345 {
+		t.Errorf("Expected connect time and endpoint, got %+v", established)
+	}
+
+	// user1 is known from the status read when subscribing
+	bytecount := events[2]
+	if bytecount.Type != EventByteCount || bytecount.Client.CommonName != "user1" || bytecount.Client.BytesReceived != 100 || bytecount.Client.BytesSent != 200 {
+		t.Errorf("Expected BYTECOUNT of user1 with 100/200 bytes, got %+v", bytecount)
+	}
+
+	disconnect := events[3]
+	if disconnect.Type != EventDisconnect || disconnect.Client.CommonName != "alice" || disconnect.Client.BytesSent != 6000 {
+		t.Errorf("Expected DISCONNECT of alice with 6000 bytes sent, got %+v", disconnect)
+	}
+}
+
+// statusAfterKill is statusResponse without user1
+const statusAfterKill = "TITLE\tOpenVPN 2.6.8 x86_64-pc-linux-gnu\r\n" +
+	"TIME\tThu Nov 27 10:30:50 2025\t1732704650\r\n" +
+	"END\r\n"
+
+// TestKill tests killing clients by common name, real address and client ID
+func TestKill(t *testing.T) {
+	tests := []struct {
+		target  Target
+		command string
+	}{
+		{Target{TargetCommonName, "user1"}, `kill "user1"`},
+		{Target{TargetRealAddress, "udp4:192.168.1.100:54321"}, "kill 192.168.1.100:54321"},
+		{Target{TargetClientID, "0"}, "client-kill 0"},
+	}
+
+	for _, tt := range tests {
+		server := startFakeServer(t, "tcp", "", map[string][]string{
+			"status 3": {statusResponse, statusAfterKill},
+			tt.command: {"SUCCESS: client-kill command succeeded\r\n"},
+		})
+		client, err := Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+
+		result, err := client.Kill(tt.target, time.Second)
+		client.Close()
+		if err != nil {
+			t.Errorf("%s: Kill failed: %v", tt.target, err)
+			continue
+		}
+		if result.Command != tt.command {
+			t.Errorf("%s: expected command '%s', got '%s'", tt.target, tt.command, result.Command)
+		}
+		if !result.Verified {
+			t.Errorf("%s: expected verified kill", tt.target)
+		}
+		if len(result.Killed) != 1 || result.Killed[0].CommonName != "user1" {
+			t.Errorf("%s: expected user1 killed, got %v", tt.target, result.Killed)
+		}
+	}
+}
+
+// TestKillIPv6 tests that IPv6 real addresses are bracketed in the command
+func TestKillIPv6(t *testing.T) {
+	status := strings.Replace(statusResponse, "\t192.168.1.100:54321\t10.8.0.2", "\t2001:db8::1:443\t10.8.0.2", 1)
+	command := "kill [2001:db8::1]:443"
+	server := startFakeServer(t, "tcp", "", map[string][]string{
+		"status 3": {status, statusAfterKill},
+		command:    {"SUCCESS: 1 client(s) killed\r\n"},
+	})
+	client, err := Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	result, err := client.Kill(Target{TargetRealAddress, "udp6:[2001:db8::1]:443"}, time.Second)
+	if err != nil {
+		t.Fatalf("Kill failed: %v", err)
+	}
+	if result.Command != command || !result.Verified {
+		t.Errorf("Expected verified '%s', got '%s'", command, result.Command)
+	}
+}
+
+// TestKillErrors tests unknown targets and clients that do not go away
+func TestKillErrors(t *testing.T) {
+	server := startFakeServer(t, "tcp", "", map[string][]string{"status 3": {statusResponse}})
+	client, err := Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	result, err := client.Kill(Target{TargetCommonName, "mallory"}, time.Second)
+	if !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Expected ErrClientNotFound, got %v", err)
+	}
+	if result.Command != "" {
+		t.Errorf("Expected no command for unknown client, got '%s'", result.Command)
+	}
+
+	server = startFakeServer(t, "tcp", "", map[string][]string{
+		"status 3":     {statusResponse},
+		`kill "user1"`: {"SUCCESS: common name 'user1' found, 1 client(s) killed\r\n"},
+	})
+	client, err = Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	result, err = client.Kill(Target{TargetCommonName, "user1"}, time.Millisecond)
+	if !errors.Is(err, ErrStillConnected) {
+		t.Errorf("Expected ErrStillConnected, got %v", err)
+	}
+	if result == nil || result.Verified || result.Response != "common name 'user1' found, 1 client(s) killed" {
+		t.Errorf("Expected unverified result with response, got %+v", result)
+	}
+}
+
+// TestKillQuoting tests that common names are sent as a single parameter
+func TestKillQuoting(t *testing.T) {
+	for cn, command := range map[string]string{
+		"John Doe":     `kill "John Doe"`,
+		`O"Brien \ IT`: `kill "O\"Brien \\ IT"`,
+	} {
+		status := strings.Replace(statusResponse, "CLIENT_LIST\tuser1\t", "CLIENT_LIST\t"+cn+"\t", 1)
+		server := startFakeServer(t, "tcp", "", map[string][]string{
+			"status 3": {status, statusAfterKill},
+			command:    {"SUCCESS: common name '" + cn + "' found, 1 client(s) killed\r\n"},
+		})
+		client, err := Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+
+		result, err := client.Kill(Target{TargetCommonName, cn}, time.Second)
+		client.Close()
+		if err != nil {
+			t.Errorf("%s: Kill failed: %v", cn, err)
+			continue
+		}
+		if result.Command != command || !result.Verified {
+			t.Errorf("%s: expected verified '%s', got '%s'", cn, command, result.Command)
+		}
+	}
+
+	// No management command is sent for values with line breaks
+	client := &Client{}
+	if _, err := client.Kill(Target{TargetCommonName, "user1\nsignal SIGTERM"}, time.Second); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("Expected ErrInvalidTarget, got %v", err)
+	}
+}
+
+// TestAppendAudit tests writing audit records as JSON lines
+func TestAppendAudit(t *testing.T) {
+	t.Setenv("SUDO_USER", "alice")
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	target := Target{TargetCommonName, "laptop-42"}
+	for i := 0; i < 2; i++ {
+		rec := NewAuditRecord(target, &KillResult{Command: "kill laptop-42", Verified: true}, nil)
+		if err := AppendAudit(path, rec); err != nil {
+			t.Fatalf("AppendAudit failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 audit lines, got %d", len(lines))
+	}
+
+	var rec AuditRecord
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatalf("Audit line is not valid JSON: %v", err)
+	}
+	if rec.Operator != "alice" || rec.Target != target || rec.Result == nil || !rec.Result.Verified {
+		t.Errorf("Unexpected audit record: %+v", rec)
+	}
+}
+
+// TestInfo tests collecting load-stats, state and version
+func TestInfo(t *testing.T) {
+	server := startFakeServer(t, "tcp", "", map[string][]string{
+		"load-stats": {"SUCCESS: nclients=1,bytesin=123456,bytesout=654321\r\n"},
+		"state":      {"1732700000,CONNECTED,SUCCESS,10.8.0.1,,,,,\r\nEND\r\n"},
+		"version":    {"OpenVPN Version: OpenVPN 2.6.8 x86_64-pc-linux-gnu [SSL (OpenSSL)] [LZO] [LZ4] [EPOLL]\r\nManagement Version: 5\r\nEND\r\n"},
+	})
+	client, err := Dial("tcp", server.listener.Addr().String(), Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	info, err := client.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.Clients != 1 || info.BytesIn != 123456 || info.BytesOut != 654321 {
+		t.Errorf("Expected load stats 1/123456/654321, got %d/%d/%d", info.Clients, info.BytesIn, info.BytesOut)
+	}
+	if info.State != "CONNECTED" || info.StateTime != 1732700000 || info.LocalIP != "10.8.0.1" {
+		t.Errorf("Expected CONNECTED since 1732700000 on 10.8.0.1, got %+v", info)
+	}
+	if !strings.HasPrefix(info.OpenVPNVersion, "OpenVPN 2.6.8 ") || info.ManagementVersion != "5" {
+		t.Errorf("Expected OpenVPN 2.6.8 and management version 5, got '%s' and '%s'", info.OpenVPNVersion, info.ManagementVersion)
+	}
+}
+
+// TestParseAddress tests splitting management addresses
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input   string
+		network string
+		address string
+	}{
+		{"127.0.0.1:7505", "tcp", "127.0.0.1:7505"},
+		{"tcp://[::1]:7505", "tcp", "[::1]:7505"},
+		{"/run/openvpn/server.sock", "unix", "/run/openvpn/server.sock"},
+		{"unix:///run/openvpn/server.sock", "unix", "/run/openvpn/server.sock"},
+	}
+
+	for _, tt := range tests {
+		network, address := ParseAddress(tt.input)
+		if network != tt.network || address != tt.address {
+			t.Errorf("ParseAddress(%q): expected %s %s, got %s %s", tt.input, tt.network, tt.address, network, address)
+		}
+	}
+}
+
+// TestReadPasswordFile tests reading the first line of a password file
+func TestReadPasswordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pw")
+	if err := os.WriteFile(path, []byte("secret\r\nignored\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+
+	password, err := ReadPasswordFile(path)
+	if err != nil {
+		t.Fatalf("ReadPasswordFile failed: %v", err)
+	}
+	if password != "secret" {
+		t.Errorf("Expected password 'secret', got '%s'", password)
+	}
+
+	if _, err := ReadPasswordFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing password file")
+	}
+}
@@ -0,0 +1,130 @@
+package parser
+
+// Column names as written by OpenVPN in HEADER lines (v2/v3) and in the
+// header rows of the v1 sections.
+const (
+	colCommonName         = "Common Name"
+	colRealAddress        = "Real Address"
+	colVirtualAddress     = "Virtual Address"
+	colVirtualIPv6Address = "Virtual IPv6 Address"
+	colBytesReceived      = "Bytes Received"
+	colBytesSent          = "Bytes Sent"
+	colConnectedSince     = "Connected Since"
+	colConnectedSinceTime = "Connected Since (time_t)"
+	colUsername           = "Username"
+	colClientID           = "Client ID"
+	colPeerID             = "Peer ID"
+	colDataCipher         = "Data Channel Cipher"
+	colLastRef            = "Last Ref"
+	colLastRefTime        = "Last Ref (time_t)"
+)
+
+// knownColumns lists the columns mapped to Client/Route fields.
+// Anything else found in a header ends up in the Extra map.
+var knownColumns = map[string]bool{
+	colCommonName:         true,
+	colRealAddress:        true,
+	colVirtualAddress:     true,
+	colVirtualIPv6Address: true,
+	colBytesReceived:      true,
+	colBytesSent:          true,
+	colConnectedSince:     true,
+	colConnectedSinceTime: true,
+	colUsername:           true,
+	colClientID:           true,
+	colPeerID:             true,
+	colDataCipher:         true,
+	colLastRef:            true,
+	colLastRefTime:        true,
+}
+
+// columnLayout maps column names to their position in a data row.
+type columnLayout struct {
+	// names are the column names in row order
+	names []string
+
+	// index maps a column name to its position in names
+	index map[string]int
+
+	// required is the minimum number of fields a data row must have
+	required int
+
+	// fromHeader is true if the layout was read from the file
+	fromHeader bool
+}
+
+// newColumnLayout builds a layout from column names in row order.
+func newColumnLayout(names []string, required int) *columnLayout {
+	layout := &columnLayout{
+		names:    names,
+		index:    make(map[string]int, len(names)),
+		required: required,
+	}
+	for i, name := range names {
+		layout.index[name] = i
+	}
+	return layout
+}
+
+// headerLayout builds a layout from a header row found in the status file.
+// Every column in the header is expected to be present in data rows.
+func headerLayout(names []string) *columnLayout {
+	layout := newColumnLayout(names, len(names))
+	layout.fromHeader = true
+	return layout
+}
+
+// Positional layouts used when the file has no header.
+var (
+	// defaultClientListLayout is the OpenVPN 2.5+ CLIENT_LIST layout;
+	// Data Channel Cipher is optional as 2.4 does not write it.
+	defaultClientListLayout = newColumnLayout([]string{
+		colCommonName, colRealAddress, colVirtualAddress, colVirtualIPv6Address,
+		colBytesReceived, colBytesSent, colConnectedSince, colConnectedSinceTime,
+		colUsername, colClientID, colPeerID, colDataCipher,
+	}, 11)
+
+	defaultRoutingTableLayout = newColumnLayout([]string{
+		colVirtualAddress, colCommonName, colRealAddress, colLastRef, colLastRefTime,
+	}, 5)
+
+	defaultClientListV1Layout = newColumnLayout([]string{
+		colCommonName, colRealAddress, colBytesReceived, colBytesSent, colConnectedSince,
+	}, 5)
+
+	defaultRoutingTableV1Layout = newColumnLayout([]string{
+		colVirtualAddress, colCommonName, colRealAddress, colLastRef,
+	}, 4)
+)
+
+// row gives access to the fields of a data line by column name.
+type row struct {
+	fields []string
+	layout *columnLayout
+}
+
+// get returns the value of the named column, or "" if the column is
+// not part of the layout or missing from the row.
+func (r row) get(name string) string {
+	i, ok := r.layout.index[name]
+	if !ok || i >= len(r.fields) {
+		return ""
+	}
+	return r.fields[i]
+}
+
+// extra returns the values of header columns not known to the parser,
+// or nil if there are none.
+func (r row) extra() map[string]string {
+	var extra map[string]string
+	for i, name := range r.layout.names {
+		if knownColumns[name] || i >= len(r.fields) {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]string)
+		}
+		extra[name] = r.fields[i]
+	}
+	return extra
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Endpoint is a parsed OpenVPN real address.
+// OpenVPN writes real addresses in several forms, depending on version and
+// address family:
+//
+//	1.2.3.4:5555
+//	udp4:1.2.3.4:5555
+//	tcp4-server:[2001:db8::1]:443
+//	2001:db8::1:443
+type Endpoint struct {
+	// Transport is the protocol prefix written by OpenVPN 2.5+
+	// (e.g. "udp4", "tcp6-server"), empty if not present
+	Transport string `json:"transport,omitempty"`
+
+	// Addr is the client's IP address
+	Addr netip.Addr `json:"addr"`
+
+	// Port is the client's source port, 0 if not present
+	Port uint16 `json:"port,omitempty"`
+}
+
+// ParseEndpoint parses an OpenVPN real address into an Endpoint.
+// Unbracketed IPv6 addresses are expected to carry a trailing port as
+// OpenVPN always writes one; if splitting it off does not give a valid
+// address the whole string is taken as the address.
+func ParseEndpoint(s string) (Endpoint, error) {
+	var ep Endpoint
+
+	rest := s
+	// Socket family markers as used in OpenVPN logs, e.g. "[AF_INET]1.2.3.4:5555"
+	rest = strings.TrimPrefix(rest, "[AF_INET]")
+	rest = strings.TrimPrefix(rest, "[AF_INET6]")
+
+	// Optional transport prefix
+	if i := strings.Index(rest, ":"); i > 0 && isTransport(rest[:i]) {
+		ep.Transport = rest[:i]
+		rest = rest[i+1:]
+	}
+
+	// IPv4 with port or bracketed IPv6 with port
+	if ap, err := netip.ParseAddrPort(rest); err == nil {
+		ep.Addr = ap.Addr()
+		ep.Port = ap.Port()
+		return ep, nil
+	}
+
+	switch strings.Count(rest, ":") {
+	case 0:
+		// IPv4 without port
+		addr, err := netip.ParseAddr(rest)
+		if err != nil {
+			return ep, fmt.Errorf("invalid address %q: %w", s, err)
+		}
+		ep.Addr = addr
+		return ep, nil
+	case 1:
+		// host:port that ParseAddrPort rejected
+		return ep, fmt.Errorf("invalid address %q", s)
+	}
+
+	// Unbracketed IPv6, split off the port
+	i := strings.LastIndex(rest, ":")
+	if addr, err := netip.ParseAddr(rest[:i]); err == nil {
+		if port, err := strconv.ParseUint(rest[i+1:], 10, 16); err == nil {
+			ep.Addr = addr
+			ep.Port = uint16(port)
+			return ep, nil
+		}
+	}
+
+	// IPv6 without port
+	addr, err := netip.ParseAddr(strings.Trim(rest, "[]"))
+	if err != nil {
+		return ep, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	ep.Addr = addr
+	return ep, nil
+}
+
+// isTransport reports whether s is an OpenVPN protocol name such as
+// "udp", "tcp4", "udp6" or "tcp4-server".
+func isTransport(s string) bool {
+	if !strings.HasPrefix(s, "udp") && !strings.HasPrefix(s, "tcp") {
+		return false
+	}
+	rest := s[3:]
+	rest = strings.TrimPrefix(rest, "4")
+	rest = strings.TrimPrefix(rest, "6")
+	return rest == "" || rest == "-server" || rest == "-client"
+}
+
+// String returns the endpoint in "[transport:]addr[:port]" form,
+// bracketing IPv6 addresses when a port is present.
+func (e Endpoint) String() string {
+	var sb strings.Builder
+	if e.Transport != "" {
+		sb.WriteString(e.Transport)
+		sb.WriteString(":")
+	}
+	if e.Port != 0 {
+		sb.WriteString(netip.AddrPortFrom(e.Addr, e.Port).String())
+	} else {
+		sb.WriteString(e.Addr.String())
+	}
+	return sb.String()
+}
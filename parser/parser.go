@@ -2,105 +2,588 @@ package parser
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// ParseOptions controls how a status file is parsed.
+type ParseOptions struct {
+	// Version is the status file version, VersionAuto detects it
+	Version StatusVersion
+
+	// Strict enables completeness and consistency checks, reported as
+	// errors: missing END marker, unknown record types, duplicate client
+	// IDs, routes to unknown clients and rows not matching their header.
+	Strict bool
+
+	// Retries is how often ParseFileWithOptions re-reads a status file
+	// that was caught while OpenVPN was rewriting it. 0 disables retries.
+	Retries int
+
+	// RetryBackoff is the delay before the first retry, doubled for every
+	// further retry. DefaultRetryBackoff is used if zero.
+	RetryBackoff time.Duration
+
+	// Location is the time zone of the human-readable times in the file,
+	// used where there is no epoch column (v1). time.Local if nil.
+	Location *time.Location
+}
+
+// DefaultRetryBackoff is the delay before the first retry of a torn read
+const DefaultRetryBackoff = 100 * time.Millisecond
+
 // ParseFile reads and parses an OpenVPN status file with specified version.
-// It returns the parsed Status and any errors encountered during parsing.
-// Parsing continues even if errors occur, collecting all errors for reporting.
-//
-// Version formats:
-// v1: Comma-separated, basic fields only (CommonName, RealAddress, BytesReceived, BytesSent, ConnectedSince)
-// v2: Comma-separated, extended fields (adds VirtualAddress, VirtualIPv6Address, Username, ClientID, PeerID, DataCipher)
-// v3: Tab-separated, same fields as v2
+// It opens the file and hands it to Parse, see Parse for details.
 func ParseFile(filepath string, version StatusVersion) (*Status, []error) {
+	return ParseFileWithOptions(filepath, ParseOptions{Version: version})
+}
+
+// ParseFileWithOptions reads and parses an OpenVPN status file.
+// OpenVPN truncates and rewrites the status file on every refresh, so a
+// read can catch it half-written. With opts.Retries set, a snapshot that
+// lacks the END marker or whose size or modification time changed while
+// reading is re-read after a short backoff. Every retry is reported in
+// the returned errors as a RetryNotice, followed by the errors of the last
+// attempt; see SplitRetryNotices.
+func ParseFileWithOptions(filepath string, opts ParseOptions) (*Status, []error) {
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	var retryErrors []error
+	for attempt := 0; ; attempt++ {
+		status, parseErrors, torn := parseFileOnce(filepath, opts)
+		if status == nil || torn == "" || opts.Retries <= 0 {
+			return status, append(retryErrors, parseErrors...)
+		}
+
+		if attempt == opts.Retries {
+			retryErrors = append(retryErrors, fmt.Errorf("torn read (%s), giving up after %d retries: %w", torn, opts.Retries, ErrTruncated))
+			return status, append(retryErrors, parseErrors...)
+		}
+
+		retryErrors = append(retryErrors, RetryNotice{Reason: torn, Retry: attempt + 1, Retries: opts.Retries, Backoff: backoff})
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// parseFileOnce opens and parses the status file once. torn describes why
+// the snapshot looks torn, or is "" if it looks consistent.
+func parseFileOnce(filepath string, opts ParseOptions) (status *Status, parseErrors []error, torn string) {
 	// Open the status file
 	file, err := os.Open(filepath)
 	if err != nil {
-		return nil, []error{fmt.Errorf("failed to open file: %w", err)}
+		return nil, []error{openError(err)}, ""
 	}
 	defer file.Close()
 
-	// Determine delimiter based on version
-	delimiter := ","
-	if version == Version3 {
-		delimiter = "\t"
+	before, err := file.Stat()
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to stat file: %w", err)}, ""
+	}
+
+	status, parseErrors = Parse(file, opts)
+
+	switch after, err := os.Stat(filepath); {
+	case err != nil:
+		torn = "file vanished while reading"
+	case after.Size() != before.Size():
+		torn = fmt.Sprintf("size changed from %d to %d bytes while reading", before.Size(), after.Size())
+	case !after.ModTime().Equal(before.ModTime()):
+		torn = "modified while reading"
+	case !status.Complete:
+		torn = "missing END marker"
 	}
+	return status, parseErrors, torn
+}
 
+// ParseFileStream opens the status file at filepath and parses it with
+// ParseStream. Unlike ParseFileWithOptions it never retries, as rows
+// already handed to h cannot be taken back.
+func ParseFileStream(filepath string, opts ParseOptions, h Handler) (*Status, []error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, []error{openError(err)}
+	}
+	defer file.Close()
+
+	return ParseStream(file, opts, h)
+}
+
+// openError wraps an error from opening the status file, adding
+// ErrFileNotFound if the file does not exist.
+func openError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to open file: %w: %w", ErrFileNotFound, err)
+	}
+	return fmt.Errorf("failed to open file: %w", err)
+}
+
+// Parse reads and parses OpenVPN status output from r, which may be a file,
+// a management interface response, an HTTP body or any other stream.
+// It returns the parsed Status and any errors encountered during parsing.
+// Parsing continues even if errors occur, collecting all errors for reporting.
+//
+// Version formats:
+// v1: Comma-separated, banner-delimited sections, basic fields only (CommonName, RealAddress, BytesReceived, BytesSent, ConnectedSince)
+// v2: Comma-separated, extended fields (adds VirtualAddress, VirtualIPv6Address, Username, ClientID, PeerID, DataCipher)
+// v3: Tab-separated, same fields as v2
+//
+// With VersionAuto the version is detected from the first non-empty line
+// and reported in Status.Version.
+//
+// The "OpenVPN STATISTICS" file of OpenVPN in client mode is read as v1
+// whatever the requested version, as OpenVPN ignores status-version in
+// client mode; its byte counters are returned in Status.Statistics.
+func Parse(r io.Reader, opts ParseOptions) (*Status, []error) {
+	c := &collector{
+		clients: make([]Client, 0),
+		routes:  make([]Route, 0),
+	}
+
+	status, parseErrors := ParseStream(r, opts, c)
+
+	status.ClientList = c.clients
+	status.RoutingTable = c.routes
+	return status, parseErrors
+}
+
+// ParseStream works like Parse but hands every client and route to h as
+// soon as it is parsed instead of collecting them, so memory use does not
+// grow with the number of clients. The returned Status has everything but
+// ClientList and RoutingTable.
+// If h returns an error, parsing stops and the error is returned last.
+func ParseStream(r io.Reader, opts ParseOptions, h Handler) (*Status, []error) {
 	// Initialize empty status structure
 	status := &Status{
-		ClientList:   make([]Client, 0),
-		RoutingTable: make([]Route, 0),
-		Time:         make([]string, 0),
+		Time: make([]string, 0),
+	}
+
+	p := &statusParser{status: status, handler: h, strict: opts.Strict, location: opts.Location}
+	if opts.Strict {
+		p.clientIDs = make(map[int64]int)
+		p.clients = make(map[string]bool)
+	}
+	if opts.Version != VersionAuto {
+		p.setVersion(opts.Version)
 	}
 
 	// Track parsing errors without stopping
 	var parseErrors []error
 	lineNum := 0
 
-	// Read file line by line
-	scanner := bufio.NewScanner(file)
+	// Read input line by line
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		lineNum++
-		line := strings.TrimSpace(scanner.Text())
+		// Tabs are kept: in v3 a trailing tab is an empty last column
+		line := strings.Trim(scanner.Text(), " \r")
 
 		// Skip empty lines
-		if line == "" {
+		if strings.TrimSpace(line) == "" {
 			continue
 		}
 
+		// An explicit version is checked against the first line
+		if !p.versionChecked {
+			p.versionChecked = true
+			if err := p.checkVersion(line, lineNum); err != nil {
+				parseErrors = append(parseErrors, err)
+			}
+		}
+
 		// Parse the line and collect any errors
-		if err := parseLine(line, status, lineNum, version, delimiter); err != nil {
+		if err := p.parseLine(line, lineNum); err != nil {
 			parseErrors = append(parseErrors, err)
 		}
+
+		// The handler refused a client or route, stop reading
+		if p.handlerErr != nil {
+			return status, append(parseErrors, p.handlerErr)
+		}
 	}
 
 	// Check for scanner errors
 	if err := scanner.Err(); err != nil {
-		parseErrors = append(parseErrors, fmt.Errorf("error reading file: %w", err))
+		parseErrors = append(parseErrors, fmt.Errorf("error reading input: %w", err))
+	}
+
+	// OpenVPN rewrites the file in place, without END it may be cut short
+	if p.strict && !status.Complete {
+		parseErrors = append(parseErrors, ParseError{
+			Line:  lineNum,
+			Field: "END",
+			Err:   fmt.Errorf("missing END marker, status file may be truncated"),
+			Kind:  KindTruncated,
+		})
+	}
+
+	if flat := FlattenErrors(parseErrors); len(flat) > 0 {
+		status.ErrorCounts = flat.Summary()
 	}
 
 	return status, parseErrors
 }
 
+// Handler receives clients and routes from ParseStream.
+type Handler interface {
+	// HandleClient is called for every client row
+	HandleClient(client Client) error
+
+	// HandleRoute is called for every routing table row
+	HandleRoute(route Route) error
+}
+
+// HandlerFuncs adapts plain functions to a Handler.
+// Nil functions ignore the rows they would receive.
+type HandlerFuncs struct {
+	Client func(client Client) error
+	Route  func(route Route) error
+}
+
+// HandleClient calls h.Client if set
+func (h HandlerFuncs) HandleClient(client Client) error {
+	if h.Client == nil {
+		return nil
+	}
+	return h.Client(client)
+}
+
+// HandleRoute calls h.Route if set
+func (h HandlerFuncs) HandleRoute(route Route) error {
+	if h.Route == nil {
+		return nil
+	}
+	return h.Route(route)
+}
+
+// collector is the Handler used by Parse to build the client and route lists.
+type collector struct {
+	clients []Client
+	routes  []Route
+}
+
+// HandleClient appends client to the client list
+func (c *collector) HandleClient(client Client) error {
+	c.clients = append(c.clients, client)
+	return nil
+}
+
+// HandleRoute appends route to the routing table
+func (c *collector) HandleRoute(route Route) error {
+	c.routes = append(c.routes, route)
+	return nil
+}
+
+// section identifies the part of a v1 status file currently being read.
+// v1 files have no per-line type prefix, so the parser has to remember
+// which banner ("ROUTING TABLE", "GLOBAL STATS", ...) it has seen last.
+type section int
+
+const (
+	sectionClientList section = iota
+	sectionRoutingTable
+	sectionGlobalStats
+	sectionStatistics
+	sectionEnd
+)
+
+// v1 section banners and header rows as written by OpenVPN
+const (
+	v1ClientListBanner   = "OpenVPN CLIENT LIST"
+	v1RoutingTableBanner = "ROUTING TABLE"
+	v1GlobalStatsBanner  = "GLOBAL STATS"
+	v1EndMarker          = "END"
+	v1UpdatedPrefix      = "Updated"
+
+	// v1StatisticsBanner starts the status file of OpenVPN in client
+	// mode, which has the v1 layout but only byte counters
+	v1StatisticsBanner = "OpenVPN STATISTICS"
+)
+
+// statusParser holds the state carried between lines of a single status file.
+type statusParser struct {
+	status    *Status
+	version   StatusVersion
+	delimiter string
+
+	// versionChecked is set once the first line has been checked
+	// against the requested version
+	versionChecked bool
+
+	// section is the current v1 section (unused for v2/v3)
+	section section
+
+	// clientLayout and routeLayout map columns of client and routing
+	// rows, taken from the file's header rows when present
+	clientLayout *columnLayout
+	routeLayout  *columnLayout
+
+	// handler receives parsed clients and routes
+	handler Handler
+
+	// handlerErr is the first error returned by handler
+	handlerErr error
+
+	// strict enables the checks of ParseOptions.Strict
+	strict bool
+
+	// location is the time zone for human-readable times
+	location *time.Location
+
+	// clientIDs maps client IDs seen so far to their line (strict only)
+	clientIDs map[int64]int
+
+	// clients holds the common name and real address of every client
+	// seen so far, to check routes against (strict only)
+	clients map[string]bool
+}
+
+// setVersion configures the delimiter and default column layouts for version.
+func (p *statusParser) setVersion(version StatusVersion) {
+	p.version = version
+	p.status.Version = version
+	p.section = sectionClientList
+
+	// Determine delimiter based on version
+	p.delimiter = ","
+	if version == Version3 {
+		p.delimiter = "\t"
+	}
+
+	p.clientLayout = defaultClientListLayout
+	p.routeLayout = defaultRoutingTableLayout
+	if version == Version1 {
+		p.clientLayout = defaultClientListV1Layout
+		p.routeLayout = defaultRoutingTableV1Layout
+	}
+}
+
+// recordTypes are the line type prefixes used by v2/v3 status files
+var recordTypes = []string{"TITLE", "TIME", "HEADER", "CLIENT_LIST", "ROUTING_TABLE", "GLOBAL_STATS"}
+
+// detectVersion guesses the status file version from its first
+// meaningful line:
+// - the "OpenVPN CLIENT LIST" banner means v1
+// - the "OpenVPN STATISTICS" banner means a client mode file, read as v1
+// - a known record type followed by a tab means v3
+// - a known record type followed by a comma means v2
+// - anything else is taken as a bare v1 client list
+func detectVersion(line string) StatusVersion {
+	if version, ok := sniffVersion(line); ok {
+		return version
+	}
+	return Version1
+}
+
+// sniffVersion is detectVersion without the fallback to v1; ok is false
+// if line is neither the v1 banner nor a known v2/v3 record.
+func sniffVersion(line string) (version StatusVersion, ok bool) {
+	if line == v1ClientListBanner || line == v1StatisticsBanner {
+		return Version1, true
+	}
+	for _, recordType := range recordTypes {
+		switch {
+		case strings.HasPrefix(line, recordType+"\t"):
+			return Version3, true
+		case strings.HasPrefix(line, recordType+","):
+			return Version2, true
+		}
+	}
+	return VersionAuto, false
+}
+
+// checkVersion reports a file whose first line clearly belongs to another
+// version than the one requested. Parsing continues with the requested
+// version, as the caller asked for it, except for client mode files,
+// which have a single format.
+func (p *statusParser) checkVersion(line string, lineNum int) error {
+	if p.version == VersionAuto {
+		return nil
+	}
+	if line == v1StatisticsBanner {
+		p.setVersion(Version1)
+		return nil
+	}
+	detected, ok := sniffVersion(line)
+	if !ok || detected == p.version {
+		return nil
+	}
+	return ParseError{
+		Line:  lineNum,
+		Field: "version",
+		Value: line,
+		Kind:  KindVersionMismatch,
+		Err:   fmt.Errorf("expected status version %d, file looks like version %d", p.version, detected),
+	}
+}
+
 // parseLine processes a single line from the status file.
 // It identifies the line type and delegates to appropriate handler.
-func parseLine(line string, status *Status, lineNum int, version StatusVersion, delimiter string) error {
+func (p *statusParser) parseLine(line string, lineNum int) error {
+	// With VersionAuto, the first meaningful line decides the version
+	if p.version == VersionAuto {
+		p.setVersion(detectVersion(line))
+	}
+
+	// v1 has no line type prefixes, sections are introduced by banners
+	if p.version == Version1 {
+		return p.parseLineV1(line, lineNum)
+	}
+
 	// Split line by delimiter
-	fields := strings.Split(line, delimiter)
+	fields := strings.Split(line, p.delimiter)
 	if len(fields) == 0 {
 		return nil
 	}
 
-	// For v1, there are no line type prefixes - all lines are client data
-	if version == Version1 {
-		return handleClientListV1(fields, status, lineNum)
-	}
-
 	// For v2/v3, identify line type by first field
 	lineType := fields[0]
 
 	switch lineType {
 	case "TITLE":
-		return handleTitle(fields, status, lineNum)
+		return handleTitle(fields, p.status, lineNum)
 	case "TIME":
-		return handleTime(fields, status, lineNum)
+		return handleTime(fields, p.status, lineNum)
 	case "HEADER":
-		// We ignore headers (Option A from discussion)
-		return nil
+		return p.handleHeader(fields, lineNum)
 	case "CLIENT_LIST":
-		return handleClientListV2V3(fields, status, lineNum)
+		return p.handleClientList(fields[1:], p.clientLayout, lineType, lineNum)
 	case "ROUTING_TABLE":
-		return handleRoutingTable(fields, status, lineNum)
+		return p.handleRoutingTable(fields[1:], p.routeLayout, lineType, lineNum)
+	case "GLOBAL_STATS":
+		return handleGlobalStats(fields[1:], p.status, lineNum)
+	case "END":
+		p.status.Complete = true
+		return nil
 	default:
 		// Unknown line type - not necessarily an error, might be future extension
+		p.status.Unknown = append(p.status.Unknown, UnknownRecord{
+			Type:   lineType,
+			Fields: fields[1:],
+			Line:   lineNum,
+		})
+		if p.strict {
+			return ParseError{
+				Line:  lineNum,
+				Field: lineType,
+				Value: line,
+				Err:   fmt.Errorf("unknown record type"),
+				Kind:  KindInconsistent,
+			}
+		}
+		return nil
+	}
+}
+
+// parseLineV1 runs the v1 section state machine.
+// A real v1 file looks like:
+//
+//	OpenVPN CLIENT LIST
+//	Updated,Thu Nov 27 10:30:45 2025
+//	Common Name,Real Address,Bytes Received,Bytes Sent,Connected Since
+//	user1,1.2.3.4:12345,1024000,2048000,Thu Nov 27 09:30:45 2025
+//	ROUTING TABLE
+//	Virtual Address,Common Name,Real Address,Last Ref
+//	10.8.0.2,user1,1.2.3.4:12345,Thu Nov 27 10:30:40 2025
+//	GLOBAL STATS
+//	Max bcast/mcast queue length,0
+//	END
+//
+// Files without banners are treated as a bare client list.
+func (p *statusParser) parseLineV1(line string, lineNum int) error {
+	// Section banners
+	switch line {
+	case v1ClientListBanner:
+		p.section = sectionClientList
+		return nil
+	case v1RoutingTableBanner:
+		p.section = sectionRoutingTable
+		return nil
+	case v1GlobalStatsBanner:
+		p.section = sectionGlobalStats
+		return nil
+	case v1StatisticsBanner:
+		p.section = sectionStatistics
+		p.status.Statistics = &ClientStatistics{}
+		return nil
+	case v1EndMarker:
+		p.section = sectionEnd
+		p.status.Complete = true
+		return nil
+	}
+
+	fields := strings.Split(line, ",")
+
+	switch p.section {
+	case sectionClientList:
+		// "Updated,<time>" has exactly two fields, a client row has five
+		if fields[0] == v1UpdatedPrefix && len(fields) == 2 {
+			p.status.Time = fields[1:]
+			return nil
+		}
+		if fields[0] == colCommonName {
+			p.clientLayout = headerLayout(fields)
+			return nil
+		}
+		return p.handleClientList(fields, p.clientLayout, "CLIENT_LIST_V1", lineNum)
+	case sectionRoutingTable:
+		if fields[0] == colVirtualAddress {
+			p.routeLayout = headerLayout(fields)
+			return nil
+		}
+		return p.handleRoutingTable(fields, p.routeLayout, "ROUTING_TABLE_V1", lineNum)
+	case sectionGlobalStats:
+		return handleGlobalStats(fields, p.status, lineNum)
+	case sectionStatistics:
+		return handleStatistics(fields, p.status, lineNum)
+	default:
+		// Anything after END is ignored
+		if p.strict {
+			return ParseError{
+				Line:  lineNum,
+				Field: v1EndMarker,
+				Value: line,
+				Err:   fmt.Errorf("data after END marker"),
+				Kind:  KindInconsistent,
+			}
+		}
 		return nil
 	}
 }
 
+// handleHeader parses HEADER lines (v2/v3 only) and switches the column
+// layout of the record type they describe.
+// Format: HEADER<delimiter><record type><delimiter><column name>...
+func (p *statusParser) handleHeader(fields []string, lineNum int) error {
+	if len(fields) < 3 {
+		return ParseError{
+			Line:  lineNum,
+			Field: "HEADER",
+			Value: strings.Join(fields, ","),
+			Err:   fmt.Errorf("expected at least 3 fields, got %d", len(fields)),
+			Kind:  KindShortRecord,
+		}
+	}
+
+	switch fields[1] {
+	case "CLIENT_LIST":
+		p.clientLayout = headerLayout(fields[2:])
+	case "ROUTING_TABLE":
+		p.routeLayout = headerLayout(fields[2:])
+	}
+	// Headers of other record types are not needed
+	return nil
+}
+
 // handleTitle parses TITLE lines (v2/v3 only).
 // Format: TITLE<delimiter><server description>
 func handleTitle(fields []string, status *Status, lineNum int) error {
@@ -110,6 +593,7 @@ func handleTitle(fields []string, status *Status, lineNum int) error {
 			Field: "TITLE",
 			Value: strings.Join(fields, ","),
 			Err:   fmt.Errorf("expected at least 2 fields, got %d", len(fields)),
+			Kind:  KindShortRecord,
 		}
 	}
 	status.Title = fields[1]
@@ -125,6 +609,7 @@ func handleTime(fields []string, status *Status, lineNum int) error {
 			Field: "TIME",
 			Value: strings.Join(fields, ","),
 			Err:   fmt.Errorf("expected at least 2 fields, got %d", len(fields)),
+			Kind:  KindShortRecord,
 		}
 	}
 	// Store all time fields (excluding the "TIME" prefix)
@@ -132,189 +617,299 @@ func handleTime(fields []string, status *Status, lineNum int) error {
 	return nil
 }
 
-// handleClientListV1 parses client lines in v1 format.
-// Format: <CommonName>,<RealAddress>,<BytesReceived>,<BytesSent>,<ConnectedSince>
-// Example: user1,1.2.3.4:12345,1024000,2048000,Mon Jan 15 10:30:45 2024
-func handleClientListV1(fields []string, status *Status, lineNum int) error {
-	// v1 CLIENT_LIST should have 5 fields
-	expectedFields := 5
-	if len(fields) < expectedFields {
+// handleGlobalStats parses a single global statistic.
+// fields holds the stat name and value without any line type prefix.
+// Format v1:    <name>,<value>
+// Format v2/v3: GLOBAL_STATS<delimiter><name><delimiter><value>
+// Example: Max bcast/mcast queue length,0
+func handleGlobalStats(fields []string, status *Status, lineNum int) error {
+	if len(fields) < 2 {
 		return ParseError{
 			Line:  lineNum,
-			Field: "CLIENT_LIST_V1",
+			Field: "GLOBAL_STATS",
 			Value: strings.Join(fields, ","),
-			Err:   fmt.Errorf("expected %d fields, got %d", expectedFields, len(fields)),
+			Err:   fmt.Errorf("expected 2 fields, got %d", len(fields)),
+			Kind:  KindShortRecord,
 		}
 	}
 
-	client := Client{}
-	var errs []error
-
-	// Parse fields
-	client.CommonName = fields[0]
-	// real address comes with port
-	aparts := strings.Split(fields[1], ":")
-	client.RealAddress = aparts[0]
-
-	// Parse numeric fields
-	if fields[2] != "" {
-		if val, err := strconv.ParseInt(fields[2], 10, 64); err != nil {
-			errs = append(errs, ParseError{Line: lineNum, Field: "bytesReceived", Value: fields[2], Err: err})
-		} else {
-			client.BytesReceived = val
-		}
-	}
-
-	if fields[3] != "" {
-		if val, err := strconv.ParseInt(fields[3], 10, 64); err != nil {
-			errs = append(errs, ParseError{Line: lineNum, Field: "bytesSent", Value: fields[3], Err: err})
-		} else {
-			client.BytesSent = val
-		}
+	if status.GlobalStats == nil {
+		status.GlobalStats = &GlobalStats{}
 	}
+	stats := status.GlobalStats
 
-	client.ConnectedSince = fields[4]
-
-	// Add client even if some fields had errors
-	status.ClientList = append(status.ClientList, client)
+	name, value := fields[0], fields[1]
 
-	// Return first error if any occurred
-	if len(errs) > 0 {
-		return errs[0]
+	switch name {
+	case "Max bcast/mcast queue length":
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return ParseError{Line: lineNum, Field: "maxBcastMcastQueueLength", Value: value, Kind: KindBadNumber, Err: err}
+		}
+		stats.MaxBcastMcastQueueLength = val
+	case "dco_enabled":
+		val, err := strconv.ParseBool(value)
+		if err != nil {
+			return ParseError{Line: lineNum, Field: "dcoEnabled", Value: value, Kind: KindBadValue, Err: err}
+		}
+		stats.DCOEnabled = &val
+	default:
+		// Keep unknown stats so newer OpenVPN versions are not silently lost
+		if stats.Extra == nil {
+			stats.Extra = make(map[string]string)
+		}
+		stats.Extra[name] = value
 	}
 	return nil
 }
 
-// handleClientListV2V3 parses CLIENT_LIST lines in v2/v3 format.
-// Format: CLIENT_LIST<delimiter><commonName><delimiter><realAddress><delimiter>...
-// Fields: CommonName, RealAddress, VirtualAddress, VirtualIPv6Address,
-//
-//	BytesReceived, BytesSent, ConnectedSince, ConnectedSinceTime,
-//	Username, ClientID, PeerID, [DataCipher]
-func handleClientListV2V3(fields []string, status *Status, lineNum int) error {
-	// CLIENT_LIST should have at least 12 fields: prefix + 11 data fields
-	// v2/v3 may have additional optional fields like DataCipher
-	minFields := 12
-	if len(fields) < minFields {
+// handleStatistics parses a line of a client mode status file.
+// Format: <name>,<value>
+// Example: TUN/TAP read bytes,1048576
+func handleStatistics(fields []string, status *Status, lineNum int) error {
+	if len(fields) < 2 {
 		return ParseError{
 			Line:  lineNum,
-			Field: "CLIENT_LIST",
+			Field: "STATISTICS",
 			Value: strings.Join(fields, ","),
-			Err:   fmt.Errorf("expected at least %d fields, got %d", minFields, len(fields)),
+			Kind:  KindShortRecord,
+			Err:   fmt.Errorf("expected 2 fields, got %d", len(fields)),
 		}
 	}
 
-	client := Client{}
-	var errs []error
-
-	// Parse each field with error collection
-	client.CommonName = fields[1]
-	// real address comes with port
-	aparts := strings.Split(fields[2], ":")
-	client.RealAddress = aparts[0]
-	client.VirtualAddress = fields[3]
-	client.VirtualIPv6Address = fields[4]
-
-	// Parse numeric fields with error handling
-	if fields[5] != "" {
-		if val, err := strconv.ParseInt(fields[5], 10, 64); err != nil {
-			errs = append(errs, ParseError{Line: lineNum, Field: "bytesReceived", Value: fields[5], Err: err})
-		} else {
-			client.BytesReceived = val
-		}
-	}
+	stats := status.Statistics
+	name, value := fields[0], fields[1]
 
-	if fields[6] != "" {
-		if val, err := strconv.ParseInt(fields[6], 10, 64); err != nil {
-			errs = append(errs, ParseError{Line: lineNum, Field: "bytesSent", Value: fields[6], Err: err})
-		} else {
-			client.BytesSent = val
+	var dst *int64
+	var field string
+	switch name {
+	case v1UpdatedPrefix:
+		status.Time = fields[1:]
+		return nil
+	case "TUN/TAP read bytes":
+		dst, field = &stats.TunTapReadBytes, "tunTapReadBytes"
+	case "TUN/TAP write bytes":
+		dst, field = &stats.TunTapWriteBytes, "tunTapWriteBytes"
+	case "TCP/UDP read bytes":
+		dst, field = &stats.LinkReadBytes, "linkReadBytes"
+	case "TCP/UDP write bytes":
+		dst, field = &stats.LinkWriteBytes, "linkWriteBytes"
+	case "Auth read bytes":
+		dst, field = &stats.AuthReadBytes, "authReadBytes"
+	default:
+		// Keep unknown counters, e.g. "pre-compress bytes"
+		if stats.Extra == nil {
+			stats.Extra = make(map[string]string)
 		}
+		stats.Extra[name] = value
+		return nil
 	}
 
-	client.ConnectedSince = fields[7]
+	var errs ParseErrors
+	parseIntField(value, field, lineNum, dst, &errs)
+	return errs.err()
+}
 
-	if fields[8] != "" {
-		if val, err := strconv.ParseInt(fields[8], 10, 64); err != nil {
-			errs = append(errs, ParseError{Line: lineNum, Field: "connectedSinceTime", Value: fields[8], Err: err})
-		} else {
-			client.ConnectedSinceTime = val
+// handleClientList parses a client row, v1 or CLIENT_LIST (v2/v3).
+// data holds the row without any line type prefix, columns are looked up
+// by name in layout so that reordered or added columns are handled.
+// recordType is used to report short rows.
+//
+// Default v1 layout: CommonName, RealAddress, BytesReceived, BytesSent, ConnectedSince
+// Default v2/v3 layout: CommonName, RealAddress, VirtualAddress, VirtualIPv6Address,
+//
+//	BytesReceived, BytesSent, ConnectedSince, ConnectedSinceTime,
+//	Username, ClientID, PeerID, [DataCipher]
+func (p *statusParser) handleClientList(data []string, layout *columnLayout, recordType string, lineNum int) error {
+	if len(data) < layout.required {
+		return ParseError{
+			Line:  lineNum,
+			Field: recordType,
+			Value: strings.Join(data, ","),
+			Err:   fmt.Errorf("expected at least %d fields, got %d", layout.required, len(data)),
+			Kind:  KindShortRecord,
 		}
 	}
 
-	client.Username = fields[9]
+	r := row{fields: data, layout: layout}
+	client := Client{}
+	var errs ParseErrors
 
-	if fields[10] != "" {
-		if val, err := strconv.ParseInt(fields[10], 10, 64); err != nil {
-			errs = append(errs, ParseError{Line: lineNum, Field: "clientId", Value: fields[10], Err: err})
-		} else {
-			client.ClientID = val
-		}
+	// Parse string fields
+	client.CommonName = r.get(colCommonName)
+	client.RealAddress = r.get(colRealAddress)
+	client.VirtualAddress = r.get(colVirtualAddress)
+	client.VirtualIPv6Address = r.get(colVirtualIPv6Address)
+	client.ConnectedSince = r.get(colConnectedSince)
+	client.Username = r.get(colUsername)
+	client.DataCipher = r.get(colDataCipher)
+
+	client.RealEndpoint = parseEndpointField(client.RealAddress, lineNum, &errs)
+
+	// Parse numeric fields with error collection
+	parseIntField(r.get(colBytesReceived), "bytesReceived", lineNum, &client.BytesReceived, &errs)
+	parseIntField(r.get(colBytesSent), "bytesSent", lineNum, &client.BytesSent, &errs)
+	parseIntField(r.get(colConnectedSinceTime), "connectedSinceTime", lineNum, &client.ConnectedSinceTime, &errs)
+	parseIntField(r.get(colClientID), "clientId", lineNum, &client.ClientID, &errs)
+	parseIntField(r.get(colPeerID), "peerId", lineNum, &client.PeerID, &errs)
+
+	// v1 has no epoch column, derive it from the human-readable time
+	if client.ConnectedSinceTime == 0 {
+		p.backfillTime(client.ConnectedSince, "connectedSince", lineNum, &client.ConnectedSinceTime, &errs)
 	}
 
-	if fields[11] != "" {
-		if val, err := strconv.ParseInt(fields[11], 10, 64); err != nil {
-			errs = append(errs, ParseError{Line: lineNum, Field: "peerId", Value: fields[11], Err: err})
-		} else {
-			client.PeerID = val
-		}
-	}
+	client.Extra = r.extra()
 
-	// Optional field: DataCipher (field 12, index 12)
-	if len(fields) > 12 && fields[12] != "" {
-		client.DataCipher = fields[12]
+	if p.strict {
+		p.checkRowLength(data, layout, recordType, lineNum, &errs)
+		p.checkClient(client, r.get(colClientID) != "", lineNum, &errs)
 	}
 
 	// Add client even if some fields had errors
-	status.ClientList = append(status.ClientList, client)
+	p.handlerErr = p.handler.HandleClient(client)
 
-	// Return first error if any occurred
-	if len(errs) > 0 {
-		return errs[0]
-	}
-	return nil
+	// Return all errors of the row
+	return errs.err()
 }
 
-// handleRoutingTable parses ROUTING_TABLE lines (v2/v3 only).
-// Format: ROUTING_TABLE<delimiter><virtualAddress><delimiter><commonName><delimiter>...
-func handleRoutingTable(fields []string, status *Status, lineNum int) error {
-	// ROUTING_TABLE should have 6 fields: prefix + 5 data fields
-	expectedFields := 6
-	if len(fields) < expectedFields {
+// handleRoutingTable parses a routing table row, v1 or ROUTING_TABLE (v2/v3).
+// data holds the row without any line type prefix, columns are looked up
+// by name in layout.
+//
+// Default v1 layout: VirtualAddress, CommonName, RealAddress, LastRef
+// Default v2/v3 layout: VirtualAddress, CommonName, RealAddress, LastRef, LastRefTime
+func (p *statusParser) handleRoutingTable(data []string, layout *columnLayout, recordType string, lineNum int) error {
+	if len(data) < layout.required {
 		return ParseError{
 			Line:  lineNum,
-			Field: "ROUTING_TABLE",
-			Value: strings.Join(fields, ","),
-			Err:   fmt.Errorf("expected %d fields, got %d", expectedFields, len(fields)),
+			Field: recordType,
+			Value: strings.Join(data, ","),
+			Err:   fmt.Errorf("expected %d fields, got %d", layout.required, len(data)),
+			Kind:  KindShortRecord,
 		}
 	}
 
+	r := row{fields: data, layout: layout}
 	route := Route{}
-	var errs []error
+	var errs ParseErrors
 
 	// Parse string fields
-	route.VirtualAddress = fields[1]
-	route.CommonName = fields[2]
-	// real address comes with port
-	aparts := strings.Split(fields[3], ":")
-	route.RealAddress = aparts[0]
-	route.LastRef = fields[4]
+	route.VirtualAddress = r.get(colVirtualAddress)
+	route.CommonName = r.get(colCommonName)
+	route.RealAddress = r.get(colRealAddress)
+	route.LastRef = r.get(colLastRef)
+	route.RealEndpoint = parseEndpointField(route.RealAddress, lineNum, &errs)
 
 	// Parse numeric field
-	if fields[5] != "" {
-		if val, err := strconv.ParseInt(fields[5], 10, 64); err != nil {
-			errs = append(errs, ParseError{Line: lineNum, Field: "lastRefTime", Value: fields[5], Err: err})
+	parseIntField(r.get(colLastRefTime), "lastRefTime", lineNum, &route.LastRefTime, &errs)
+
+	// v1 has no epoch column, derive it from the human-readable time
+	if route.LastRefTime == 0 {
+		p.backfillTime(route.LastRef, "lastRef", lineNum, &route.LastRefTime, &errs)
+	}
+
+	route.Extra = r.extra()
+
+	if p.strict {
+		p.checkRowLength(data, layout, recordType, lineNum, &errs)
+		p.checkRoute(route, lineNum, &errs)
+	}
+
+	// Add route even if some fields had errors
+	p.handlerErr = p.handler.HandleRoute(route)
+
+	// Return all errors of the row
+	return errs.err()
+}
+
+// checkRowLength reports rows with more fields than their header (strict only).
+// Rows with fewer fields are always rejected by the handlers.
+func (p *statusParser) checkRowLength(data []string, layout *columnLayout, recordType string, lineNum int, errs *ParseErrors) {
+	if layout.fromHeader && len(data) != len(layout.names) {
+		*errs = append(*errs, ParseError{
+			Line:  lineNum,
+			Field: recordType,
+			Value: strings.Join(data, ","),
+			Err:   fmt.Errorf("header has %d columns, row has %d fields", len(layout.names), len(data)),
+			Kind:  KindInconsistent,
+		})
+	}
+}
+
+// checkClient reports duplicate client IDs and remembers the client for
+// checkRoute (strict only). hasID is false if the row had no client ID.
+func (p *statusParser) checkClient(client Client, hasID bool, lineNum int, errs *ParseErrors) {
+	if hasID {
+		if first, ok := p.clientIDs[client.ClientID]; ok {
+			*errs = append(*errs, ParseError{
+				Line:  lineNum,
+				Field: "clientId",
+				Value: strconv.FormatInt(client.ClientID, 10),
+				Err:   fmt.Errorf("duplicate client ID, first seen on line %d", first),
+				Kind:  KindInconsistent,
+			})
 		} else {
-			route.LastRefTime = val
+			p.clientIDs[client.ClientID] = lineNum
 		}
 	}
+	p.clients[clientKey(client.CommonName, client.RealAddress)] = true
+}
 
-	// Add route even if some fields had errors
-	status.RoutingTable = append(status.RoutingTable, route)
+// checkRoute reports routes pointing to a client not in the client list (strict only).
+func (p *statusParser) checkRoute(route Route, lineNum int, errs *ParseErrors) {
+	if !p.clients[clientKey(route.CommonName, route.RealAddress)] {
+		*errs = append(*errs, ParseError{
+			Line:  lineNum,
+			Field: "commonName",
+			Value: route.CommonName,
+			Err:   fmt.Errorf("route %s references unknown client at %s", route.VirtualAddress, route.RealAddress),
+			Kind:  KindInconsistent,
+		})
+	}
+}
 
-	// Return first error if any occurred
-	if len(errs) > 0 {
-		return errs[0]
+// clientKey identifies a client session by common name and real address,
+// as common names alone are not unique with duplicate-cn.
+func clientKey(commonName, realAddress string) string {
+	return commonName + "\x00" + realAddress
+}
+
+// parseEndpointField parses a real address. Empty or invalid values give nil,
+// invalid ones are also appended to errs as a ParseError.
+func parseEndpointField(value string, lineNum int, errs *ParseErrors) *Endpoint {
+	if value == "" {
+		return nil
 	}
-	return nil
+	ep, err := ParseEndpoint(value)
+	if err != nil {
+		*errs = append(*errs, ParseError{Line: lineNum, Field: "realAddress", Value: value, Kind: KindBadValue, Err: err})
+		return nil
+	}
+	return &ep
+}
+
+// backfillTime sets dst to the Unix time of the human-readable time value.
+// It is used when the epoch column is missing or invalid; if value cannot
+// be parsed either, a ParseError for field is appended to errs.
+func (p *statusParser) backfillTime(value, field string, lineNum int, dst *int64, errs *ParseErrors) {
+	t, err := ParseTime(value, p.location)
+	if err != nil {
+		*errs = append(*errs, ParseError{Line: lineNum, Field: field, Value: value, Kind: KindBadValue, Err: fmt.Errorf("no epoch time and %w", err)})
+		return
+	}
+	*dst = t.Unix()
+}
+
+// parseIntField parses value into dst. Empty values leave dst untouched,
+// invalid ones are appended to errs as a ParseError for field.
+func parseIntField(value, field string, lineNum int, dst *int64, errs *ParseErrors) {
+	if value == "" {
+		return
+	}
+	val, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		*errs = append(*errs, ParseError{Line: lineNum, Field: field, Value: value, Kind: KindBadNumber, Err: err})
+		return
+	}
+	*dst = val
 }
@@ -1,8 +1,14 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestParseFileV1 tests parsing of version 1 status files
@@ -43,6 +49,122 @@ bob,198.51.100.25:33456,15728640,31457280,Wed Nov 26 22:45:00 2025`
 	}
 }
 
+// TestParseFileV1Sections tests parsing of a complete v1 file as written by OpenVPN
+func TestParseFileV1Sections(t *testing.T) {
+	content := `OpenVPN CLIENT LIST
+Updated,Thu Nov 27 10:30:45 2025
+Common Name,Real Address,Bytes Received,Bytes Sent,Connected Since
+user1,192.168.1.100:54321,1048576,2097152,Thu Nov 27 09:30:45 2025
+alice,203.0.113.50:12345,5242880,10485760,Thu Nov 27 08:15:30 2025
+ROUTING TABLE
+Virtual Address,Common Name,Real Address,Last Ref
+10.8.0.2,user1,192.168.1.100:54321,Thu Nov 27 10:30:40 2025
+10.8.0.6,alice,203.0.113.50:12345,Thu Nov 27 10:30:41 2025
+GLOBAL STATS
+Max bcast/mcast queue length,0
+END
+`
+
+	tmpfile := createTempFile(t, "status-v1-sections-*.log", content)
+	defer os.Remove(tmpfile)
+
+	status, errors := ParseFile(tmpfile, Version1)
+
+	if len(errors) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errors), errors)
+	}
+
+	if len(status.Time) != 1 || status.Time[0] != "Thu Nov 27 10:30:45 2025" {
+		t.Errorf("Expected Time from Updated line, got %v", status.Time)
+	}
+
+	if len(status.ClientList) != 2 {
+		t.Fatalf("Expected 2 clients, got %d", len(status.ClientList))
+	}
+	if status.ClientList[1].CommonName != "alice" {
+		t.Errorf("Expected CommonName 'alice', got '%s'", status.ClientList[1].CommonName)
+	}
+
+	if len(status.RoutingTable) != 2 {
+		t.Fatalf("Expected 2 routing entries, got %d", len(status.RoutingTable))
+	}
+	route := status.RoutingTable[0]
+	if route.VirtualAddress != "10.8.0.2" {
+		t.Errorf("Expected route VirtualAddress '10.8.0.2', got '%s'", route.VirtualAddress)
+	}
+	if route.CommonName != "user1" {
+		t.Errorf("Expected route CommonName 'user1', got '%s'", route.CommonName)
+	}
+	if route.LastRef != "Thu Nov 27 10:30:40 2025" {
+		t.Errorf("Expected route LastRef 'Thu Nov 27 10:30:40 2025', got '%s'", route.LastRef)
+	}
+
+	if status.GlobalStats == nil {
+		t.Fatal("Expected GlobalStats from GLOBAL STATS section, got nil")
+	}
+	if status.GlobalStats.MaxBcastMcastQueueLength != 0 {
+		t.Errorf("Expected MaxBcastMcastQueueLength 0, got %d", status.GlobalStats.MaxBcastMcastQueueLength)
+	}
+}
+
+// TestParseClientStatistics tests the status file of OpenVPN in client mode
+func TestParseClientStatistics(t *testing.T) {
+	content := `OpenVPN STATISTICS
+Updated,Thu Nov 27 10:30:45 2025
+TUN/TAP read bytes,1048576
+TUN/TAP write bytes,2097152
+TCP/UDP read bytes,2202009
+TCP/UDP write bytes,1153433
+Auth read bytes,2097200
+pre-compress bytes,0
+END`
+
+	status, errs := Parse(strings.NewReader(content), ParseOptions{})
+	if len(errs) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errs), errs)
+	}
+	if status.Statistics == nil {
+		t.Fatal("Expected client statistics")
+	}
+	if status.Version != Version1 {
+		t.Errorf("Expected client mode file to be read as v1, got %d", status.Version)
+	}
+	if !status.Complete {
+		t.Error("Expected Complete true")
+	}
+	if len(status.Time) != 1 || status.Time[0] != "Thu Nov 27 10:30:45 2025" {
+		t.Errorf("Expected updated time, got %v", status.Time)
+	}
+
+	stats := status.Statistics
+	if stats.TunTapReadBytes != 1048576 || stats.TunTapWriteBytes != 2097152 {
+		t.Errorf("Expected TUN/TAP bytes 1048576/2097152, got %d/%d", stats.TunTapReadBytes, stats.TunTapWriteBytes)
+	}
+	if stats.LinkReadBytes != 2202009 || stats.LinkWriteBytes != 1153433 {
+		t.Errorf("Expected TCP/UDP bytes 2202009/1153433, got %d/%d", stats.LinkReadBytes, stats.LinkWriteBytes)
+	}
+	if stats.AuthReadBytes != 2097200 {
+		t.Errorf("Expected auth read bytes 2097200, got %d", stats.AuthReadBytes)
+	}
+	if stats.Extra["pre-compress bytes"] != "0" {
+		t.Errorf("Expected pre-compress bytes in Extra, got %v", stats.Extra)
+	}
+	if len(status.ClientList) != 0 {
+		t.Errorf("Expected no clients, got %d", len(status.ClientList))
+	}
+
+	// OpenVPN ignores status-version in client mode
+	status, errs = Parse(strings.NewReader(content), ParseOptions{Version: Version3})
+	if len(errs) > 0 || status.Statistics == nil {
+		t.Errorf("Expected client mode file to be read with explicit version 3, got %v", errs)
+	}
+
+	_, errs = Parse(strings.NewReader("OpenVPN STATISTICS\nTUN/TAP read bytes,lots\nEND\n"), ParseOptions{})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrBadNumber) {
+		t.Errorf("Expected a bad number error, got %v", errs)
+	}
+}
+
 // TestParseFileV2 tests parsing of version 2 status files (comma-separated)
 func TestParseFileV2(t *testing.T) {
 	content := `TITLE,OpenVPN Server Status
@@ -130,6 +252,393 @@ func TestParseFileV3(t *testing.T) {
 	}
 }
 
+// TestParseV3EmptyLastColumn tests that a trailing tab of an empty last
+// column is not mistaken for whitespace and the row matches its HEADER
+func TestParseV3EmptyLastColumn(t *testing.T) {
+	content := "HEADER\tCLIENT_LIST\tCommon Name\tReal Address\tVirtual Address\tVirtual IPv6 Address\tBytes Received\tBytes Sent\tConnected Since\tConnected Since (time_t)\tUsername\tClient ID\tPeer ID\tData Channel Cipher\n" +
+		"CLIENT_LIST\tuser1\t192.168.1.100:54321\t10.8.0.2\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\t0\t\r\n" +
+		"END\n"
+
+	status, errors := Parse(strings.NewReader(content), ParseOptions{Version: Version3, Strict: true})
+
+	if len(errors) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errors), errors)
+	}
+	if len(status.ClientList) != 1 {
+		t.Fatalf("Expected 1 client, got %d", len(status.ClientList))
+	}
+	if status.ClientList[0].DataCipher != "" {
+		t.Errorf("Expected empty DataCipher, got '%s'", status.ClientList[0].DataCipher)
+	}
+}
+
+// TestParseFileHeaderMapping tests that columns are mapped by HEADER names
+func TestParseFileHeaderMapping(t *testing.T) {
+	content := `HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Bytes Sent,Bytes Received,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Future Column,Data Channel Cipher
+CLIENT_LIST,user1,192.168.1.100:54321,10.8.0.2,2097152,1048576,Thu Nov 27 09:30:45 2025,1732700645,user1,3,4,future,CHACHA20-POLY1305
+HEADER,ROUTING_TABLE,Virtual Address,Common Name,Real Address,Last Ref,Last Ref (time_t),Route Extra
+ROUTING_TABLE,10.8.0.2,user1,192.168.1.100:54321,Thu Nov 27 10:30:45 2025,1732704645,x`
+
+	tmpfile := createTempFile(t, "status-header-*.log", content)
+	defer os.Remove(tmpfile)
+
+	status, errors := ParseFile(tmpfile, Version2)
+
+	if len(errors) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errors), errors)
+	}
+
+	if len(status.ClientList) != 1 {
+		t.Fatalf("Expected 1 client, got %d", len(status.ClientList))
+	}
+
+	client := status.ClientList[0]
+	if client.BytesReceived != 1048576 {
+		t.Errorf("Expected BytesReceived 1048576, got %d", client.BytesReceived)
+	}
+	if client.BytesSent != 2097152 {
+		t.Errorf("Expected BytesSent 2097152, got %d", client.BytesSent)
+	}
+	if client.VirtualIPv6Address != "" {
+		t.Errorf("Expected empty VirtualIPv6Address, got '%s'", client.VirtualIPv6Address)
+	}
+	if client.ClientID != 3 || client.PeerID != 4 {
+		t.Errorf("Expected ClientID 3 and PeerID 4, got %d and %d", client.ClientID, client.PeerID)
+	}
+	if client.DataCipher != "CHACHA20-POLY1305" {
+		t.Errorf("Expected DataCipher 'CHACHA20-POLY1305', got '%s'", client.DataCipher)
+	}
+	if client.Extra["Future Column"] != "future" {
+		t.Errorf("Expected Extra[Future Column] 'future', got %v", client.Extra)
+	}
+
+	if len(status.RoutingTable) != 1 {
+		t.Fatalf("Expected 1 routing entry, got %d", len(status.RoutingTable))
+	}
+	if status.RoutingTable[0].Extra["Route Extra"] != "x" {
+		t.Errorf("Expected route Extra[Route Extra] 'x', got %v", status.RoutingTable[0].Extra)
+	}
+}
+
+// TestParseFileHeaderShortRow tests rows with fewer fields than their header
+func TestParseFileHeaderShortRow(t *testing.T) {
+	content := `HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher
+CLIENT_LIST,user1,192.168.1.100:54321,10.8.0.2,,1048576,2097152,Thu Nov 27 09:30:45 2025,1732700645,user1,0,0`
+
+	tmpfile := createTempFile(t, "status-header-short-*.log", content)
+	defer os.Remove(tmpfile)
+
+	status, errors := ParseFile(tmpfile, Version2)
+
+	if len(errors) != 1 {
+		t.Errorf("Expected 1 error for short row, got %d: %v", len(errors), errors)
+	}
+	if len(status.ClientList) != 0 {
+		t.Errorf("Expected short row to be skipped, got %d clients", len(status.ClientList))
+	}
+}
+
+// TestParseFileGlobalStats tests parsing of GLOBAL_STATS lines (v2/v3)
+func TestParseFileGlobalStats(t *testing.T) {
+	content := "TITLE\tOpenVPN 2.6.8 x86_64-pc-linux-gnu\n" +
+		"GLOBAL_STATS\tMax bcast/mcast queue length\t7\n" +
+		"GLOBAL_STATS\tdco_enabled\t1\n" +
+		"GLOBAL_STATS\tfuture_stat\t42\n" +
+		"END\n"
+
+	tmpfile := createTempFile(t, "status-global-stats-*.log", content)
+	defer os.Remove(tmpfile)
+
+	status, errors := ParseFile(tmpfile, Version3)
+
+	if len(errors) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errors), errors)
+	}
+
+	stats := status.GlobalStats
+	if stats == nil {
+		t.Fatal("Expected GlobalStats, got nil")
+	}
+	if stats.MaxBcastMcastQueueLength != 7 {
+		t.Errorf("Expected MaxBcastMcastQueueLength 7, got %d", stats.MaxBcastMcastQueueLength)
+	}
+	if stats.DCOEnabled == nil || !*stats.DCOEnabled {
+		t.Errorf("Expected DCOEnabled true, got %v", stats.DCOEnabled)
+	}
+	if stats.Extra["future_stat"] != "42" {
+		t.Errorf("Expected Extra[future_stat] '42', got '%s'", stats.Extra["future_stat"])
+	}
+}
+
+// TestParseFileVersionAuto tests version detection from file contents
+func TestParseFileVersionAuto(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected StatusVersion
+		clients  int
+	}{
+		{
+			name:     "v1 banner",
+			content:  "OpenVPN CLIENT LIST\nUpdated,Thu Nov 27 10:30:45 2025\nCommon Name,Real Address,Bytes Received,Bytes Sent,Connected Since\nuser1,192.168.1.100:54321,1048576,2097152,Thu Nov 27 09:30:45 2025\nEND\n",
+			expected: Version1,
+			clients:  1,
+		},
+		{
+			name:     "v1 bare client list",
+			content:  "user1,192.168.1.100:54321,1048576,2097152,Thu Nov 27 09:30:45 2025\n",
+			expected: Version1,
+			clients:  1,
+		},
+		{
+			name:     "v2",
+			content:  "\nTITLE,OpenVPN Server\nCLIENT_LIST,user1,192.168.1.100:54321,10.8.0.2,,1048576,2097152,Thu Nov 27 09:30:45 2025,1732700645,user1,0,0,AES-256-GCM\n",
+			expected: Version2,
+			clients:  1,
+		},
+		{
+			name:     "v3",
+			content:  "CLIENT_LIST\tuser1\t192.168.1.100:54321\t10.8.0.2\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\t0\tAES-256-GCM\n",
+			expected: Version3,
+			clients:  1,
+		},
+		{
+			name:     "empty",
+			content:  "",
+			expected: VersionAuto,
+			clients:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		tmpfile := createTempFile(t, "status-auto-*.log", tt.content)
+		status, errors := ParseFile(tmpfile, VersionAuto)
+		os.Remove(tmpfile)
+
+		if len(errors) > 0 {
+			t.Errorf("%s: expected no errors, got %d: %v", tt.name, len(errors), errors)
+		}
+		if status.Version != tt.expected {
+			t.Errorf("%s: expected Version %d, got %d", tt.name, tt.expected, status.Version)
+		}
+		if len(status.ClientList) != tt.clients {
+			t.Errorf("%s: expected %d clients, got %d", tt.name, tt.clients, len(status.ClientList))
+		}
+	}
+}
+
+// TestParseReader tests parsing from an io.Reader without touching the filesystem
+func TestParseReader(t *testing.T) {
+	content := "TITLE\tOpenVPN Server Status\n" +
+		"CLIENT_LIST\tuser1\t192.168.1.100:54321\t10.8.0.2\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\t0\tAES-256-GCM\n" +
+		"END\n"
+
+	status, errors := Parse(strings.NewReader(content), ParseOptions{Version: Version3})
+
+	if len(errors) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errors), errors)
+	}
+	if status.Title != "OpenVPN Server Status" {
+		t.Errorf("Expected Title 'OpenVPN Server Status', got '%s'", status.Title)
+	}
+	if len(status.ClientList) != 1 {
+		t.Errorf("Expected 1 client, got %d", len(status.ClientList))
+	}
+}
+
+// TestParseStream tests that ParseStream hands rows to the handler instead of collecting them
+func TestParseStream(t *testing.T) {
+	var clients []string
+	var routes []string
+	handler := HandlerFuncs{
+		Client: func(client Client) error {
+			clients = append(clients, client.CommonName)
+			return nil
+		},
+		Route: func(route Route) error {
+			routes = append(routes, route.VirtualAddress)
+			return nil
+		},
+	}
+
+	status, errs := ParseStream(strings.NewReader(largeStatusV3(3)), ParseOptions{Version: Version3}, handler)
+
+	if len(errs) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errs), errs)
+	}
+	if len(clients) != 3 || len(routes) != 3 {
+		t.Errorf("Expected 3 clients and 3 routes, got %d and %d", len(clients), len(routes))
+	}
+	if len(status.ClientList) != 0 || len(status.RoutingTable) != 0 {
+		t.Error("Expected ParseStream to leave client list and routing table empty")
+	}
+	if status.Title != "OpenVPN Server" {
+		t.Errorf("Expected Title 'OpenVPN Server', got '%s'", status.Title)
+	}
+}
+
+// TestParseStreamHandlerError tests that a handler error stops parsing
+func TestParseStreamHandlerError(t *testing.T) {
+	stop := errors.New("stop")
+	clients := 0
+	handler := HandlerFuncs{Client: func(Client) error {
+		clients++
+		return stop
+	}}
+
+	_, errs := ParseStream(strings.NewReader(largeStatusV3(3)), ParseOptions{Version: Version3}, handler)
+
+	if clients != 1 {
+		t.Errorf("Expected parsing to stop after 1 client, got %d", clients)
+	}
+	if len(errs) == 0 || !errors.Is(errs[len(errs)-1], stop) {
+		t.Errorf("Expected handler error to be returned last, got %v", errs)
+	}
+}
+
+// TestParseStrict tests the completeness and consistency checks of strict mode
+func TestParseStrict(t *testing.T) {
+	content := `HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher
+CLIENT_LIST,user1,192.168.1.100:54321,10.8.0.2,,1048576,2097152,Thu Nov 27 09:30:45 2025,1732700645,user1,0,0,AES-256-GCM
+CLIENT_LIST,alice,203.0.113.50:12345,10.8.0.6,,5242880,10485760,Thu Nov 27 08:15:30 2025,1732696530,alice,0,1,AES-256-GCM
+CLIENT_LIST,carol,203.0.113.51:12345,10.8.0.14,,5242880,10485760,Thu Nov 27 08:15:30 2025,1732696530,carol,2,2,AES-256-GCM,extra
+NEW_RECORD,something
+ROUTING_TABLE,10.8.0.2,user1,192.168.1.100:54321,Thu Nov 27 10:30:45 2025,1732704645
+ROUTING_TABLE,10.8.0.10,bob,198.51.100.25:33456,Thu Nov 27 10:30:45 2025,1732704645`
+
+	status, errs := Parse(strings.NewReader(content), ParseOptions{Version: Version2})
+	if len(errs) > 0 {
+		t.Errorf("Expected no errors without strict mode, got %d: %v", len(errs), errs)
+	}
+	if status.Complete {
+		t.Error("Expected Complete false without END marker")
+	}
+
+	status, errs = Parse(strings.NewReader(content), ParseOptions{Version: Version2, Strict: true})
+
+	expected := []string{
+		"line 3, field clientId",    // duplicate client ID
+		"line 4, field CLIENT_LIST", // more fields than header
+		"line 5, field NEW_RECORD",  // unknown record type
+		"line 7, field commonName",  // route to unknown client
+		"line 7, field END",         // missing END
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors in strict mode, got %d: %v", len(expected), len(errs), errs)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(errs[i].Error(), prefix) {
+			t.Errorf("Expected error %d to start with '%s', got '%v'", i, prefix, errs[i])
+		}
+	}
+	if len(status.ClientList) != 3 {
+		t.Errorf("Expected 3 clients in strict mode, got %d", len(status.ClientList))
+	}
+}
+
+// TestParseComplete tests that the END marker marks the status as complete
+func TestParseComplete(t *testing.T) {
+	inputs := map[StatusVersion]string{
+		Version1: "OpenVPN CLIENT LIST\nUpdated,Thu Nov 27 10:30:45 2025\nROUTING TABLE\nGLOBAL STATS\nEND\n",
+		Version2: "TITLE,OpenVPN Server\nEND\n",
+		Version3: "TITLE\tOpenVPN Server\nEND\n",
+	}
+
+	for version, content := range inputs {
+		status, errs := Parse(strings.NewReader(content), ParseOptions{Version: version, Strict: true})
+		if len(errs) > 0 {
+			t.Errorf("v%d: expected no errors, got %d: %v", version, len(errs), errs)
+		}
+		if !status.Complete {
+			t.Errorf("v%d: expected Complete true", version)
+		}
+	}
+}
+
+// TestParseUnknownRecords tests that records of unknown type are kept
+func TestParseUnknownRecords(t *testing.T) {
+	content := "TITLE\tOpenVPN Server\n" +
+		"NEW_RECORD\ta\tb\n" +
+		"CLIENT_LIST\tuser1\t192.168.1.100:54321\t10.8.0.2\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\t0\tAES-256-GCM\n" +
+		"NEW_RECORD\tc\n" +
+		"END\n"
+
+	status, errs := Parse(strings.NewReader(content), ParseOptions{Version: Version3})
+	if len(errs) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errs), errs)
+	}
+	if len(status.ClientList) != 1 {
+		t.Errorf("Expected 1 client, got %d", len(status.ClientList))
+	}
+	if len(status.Unknown) != 2 {
+		t.Fatalf("Expected 2 unknown records, got %d", len(status.Unknown))
+	}
+
+	rec := status.Unknown[0]
+	if rec.Type != "NEW_RECORD" || rec.Line != 2 || strings.Join(rec.Fields, ",") != "a,b" {
+		t.Errorf("Expected NEW_RECORD on line 2 with fields a,b, got %s on line %d with fields %v", rec.Type, rec.Line, rec.Fields)
+	}
+	if status.Unknown[1].Line != 4 {
+		t.Errorf("Expected second unknown record on line 4, got %d", status.Unknown[1].Line)
+	}
+}
+
+// TestParseFileRetryTornRead tests that a half-written status file is re-read
+func TestParseFileRetryTornRead(t *testing.T) {
+	complete := "TITLE\tOpenVPN Server\n" +
+		"CLIENT_LIST\tuser1\t192.168.1.100:54321\t10.8.0.2\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\t0\tAES-256-GCM\n" +
+		"END\n"
+
+	// OpenVPN is caught after writing the title only
+	tmpfile := createTempFile(t, "status-torn-*.log", "TITLE\tOpenVPN Server\n")
+	defer os.Remove(tmpfile)
+
+	// ... and finishes the file before the first retry
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(10 * time.Millisecond)
+		os.WriteFile(tmpfile, []byte(complete), 0644)
+	}()
+
+	status, errs := ParseFileWithOptions(tmpfile, ParseOptions{Version: Version3, Retries: 3, RetryBackoff: 50 * time.Millisecond})
+	<-done
+
+	if !status.Complete {
+		t.Error("Expected complete status after retry")
+	}
+	if len(status.ClientList) != 1 {
+		t.Errorf("Expected 1 client after retry, got %d", len(status.ClientList))
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "torn read") {
+		t.Errorf("Expected 1 retry reported in errors, got %v", errs)
+	}
+
+	// A successful retry is a notice, not an error
+	notices, rest := SplitRetryNotices(errs)
+	if len(notices) != 1 || notices[0].Retry != 1 || len(rest) != 0 {
+		t.Errorf("Expected 1 retry notice and no errors, got %v and %v", notices, rest)
+	}
+}
+
+// TestParseFileRetryGiveUp tests that retries are bounded
+func TestParseFileRetryGiveUp(t *testing.T) {
+	tmpfile := createTempFile(t, "status-torn-*.log", "TITLE\tOpenVPN Server\n")
+	defer os.Remove(tmpfile)
+
+	status, errs := ParseFileWithOptions(tmpfile, ParseOptions{Version: Version3, Retries: 2, RetryBackoff: time.Millisecond})
+
+	if status.Complete {
+		t.Error("Expected incomplete status")
+	}
+	if len(errs) != 3 {
+		t.Errorf("Expected 2 retries and a final error, got %d: %v", len(errs), errs)
+	}
+	if len(errs) > 0 && !errors.Is(errs[len(errs)-1], ErrTruncated) {
+		t.Errorf("Expected final error to match ErrTruncated, got %v", errs[len(errs)-1])
+	}
+}
+
 // TestParseFileEmpty tests parsing of empty file
 func TestParseFileEmpty(t *testing.T) {
 	tmpfile := createTempFile(t, "status-empty-*.log", "")
@@ -155,6 +664,53 @@ func TestParseFileInvalidPath(t *testing.T) {
 	}
 }
 
+// TestParseErrorKinds tests that errors match their sentinel with errors.Is
+func TestParseErrorKinds(t *testing.T) {
+	_, errs := ParseFile("/nonexistent/path/status.log", Version3)
+	if len(errs) != 1 || !errors.Is(errs[0], ErrFileNotFound) || !errors.Is(errs[0], os.ErrNotExist) {
+		t.Errorf("Expected ErrFileNotFound wrapping os.ErrNotExist, got %v", errs)
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		opts     ParseOptions
+		sentinel error
+		kind     ErrorKind
+	}{
+		{"short record", "CLIENT_LIST\tuser1\t192.168.1.100:54321\n", ParseOptions{Version: Version3}, ErrShortRecord, KindShortRecord},
+		{"bad number", "ROUTING_TABLE\t10.8.0.2\tuser1\t192.168.1.100:54321\tThu Nov 27 10:30:45 2025\tnot_a_number\n", ParseOptions{Version: Version3}, ErrBadNumber, KindBadNumber},
+		{"version mismatch", "TITLE\tOpenVPN Server\nEND\n", ParseOptions{Version: Version2}, ErrVersionMismatch, KindVersionMismatch},
+		{"truncated", "TITLE\tOpenVPN Server\n", ParseOptions{Version: Version3, Strict: true}, ErrTruncated, KindTruncated},
+	}
+
+	for _, tt := range tests {
+		_, errs := Parse(strings.NewReader(tt.content), tt.opts)
+		if len(errs) != 1 {
+			t.Errorf("%s: expected 1 error, got %d: %v", tt.name, len(errs), errs)
+			continue
+		}
+		if !errors.Is(errs[0], tt.sentinel) {
+			t.Errorf("%s: expected error to match %v, got %v", tt.name, tt.sentinel, errs[0])
+		}
+		var parseErr ParseError
+		if !errors.As(errs[0], &parseErr) || parseErr.Kind != tt.kind {
+			t.Errorf("%s: expected kind %d, got %v", tt.name, tt.kind, errs[0])
+		}
+		if errors.Is(errs[0], ErrFileNotFound) {
+			t.Errorf("%s: expected error not to match ErrFileNotFound", tt.name)
+		}
+	}
+
+	// A bare v1 client list gives no confident version, so no mismatch is reported
+	_, errs = Parse(strings.NewReader("user1,192.168.1.100:54321,1048576,2097152,Thu Nov 27 09:30:45 2025\n"), ParseOptions{Version: Version2})
+	for _, err := range errs {
+		if errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("Expected no version mismatch for an unrecognized first line, got %v", err)
+		}
+	}
+}
+
 // TestParseFileMalformed tests handling of malformed data
 func TestParseFileMalformed(t *testing.T) {
 	content := `TITLE	OpenVPN Server
@@ -174,6 +730,41 @@ CLIENT_LIST	user1	192.168.1.100:54321	10.8.0.2		invalid_number	2097152	Thu Nov 2
 	}
 }
 
+// TestParseAllFieldErrors tests that every invalid field of a row is reported
+func TestParseAllFieldErrors(t *testing.T) {
+	content := "CLIENT_LIST\tuser1\t192.168.1.100:54321\t10.8.0.2\t\tbad_rx\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\tbad_peer\n" +
+		"CLIENT_LIST\tuser2\t192.168.1.101:54321\t10.8.0.3\t\tbad_rx\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser2\t1\t1\n"
+
+	status, errs := Parse(strings.NewReader(content), ParseOptions{Version: Version3})
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+
+	var group ParseErrors
+	if !errors.As(errs[0], &group) {
+		t.Fatalf("Expected ParseErrors for line 1, got %T", errs[0])
+	}
+	if len(group) != 2 || group[0].Field != "bytesReceived" || group[1].Field != "peerId" {
+		t.Errorf("Expected bytesReceived and peerId errors, got %v", group)
+	}
+	if !errors.Is(errs[0], strconv.ErrSyntax) {
+		t.Error("Expected errors.Is to find strconv.ErrSyntax")
+	}
+
+	var single ParseError
+	if !errors.As(errs[1], &single) || single.Line != 2 {
+		t.Errorf("Expected a single ParseError for line 2, got %v", errs[1])
+	}
+
+	flat := FlattenErrors(errs)
+	if len(flat) != 3 {
+		t.Errorf("Expected 3 flattened errors, got %d", len(flat))
+	}
+	if status.ErrorCounts["bytesReceived"] != 2 || status.ErrorCounts["peerId"] != 1 {
+		t.Errorf("Expected 2 bytesReceived and 1 peerId errors, got %v", status.ErrorCounts)
+	}
+}
+
 // TestParseFileV1InsufficientFields tests v1 with missing fields
 func TestParseFileV1InsufficientFields(t *testing.T) {
 	content := `user1,192.168.1.100:54321,1048576`
@@ -233,6 +824,139 @@ func TestParseFileIPv6(t *testing.T) {
 	}
 }
 
+// TestParseEndpoint tests parsing of the real address forms written by OpenVPN
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		input     string
+		transport string
+		addr      string
+		port      uint16
+	}{
+		{"192.168.1.100:54321", "", "192.168.1.100", 54321},
+		{"192.168.1.100", "", "192.168.1.100", 0},
+		{"udp4:1.2.3.4:5555", "udp4", "1.2.3.4", 5555},
+		{"tcp4-server:[2001:db8::1]:443", "tcp4-server", "2001:db8::1", 443},
+		{"[2001:db8::1]:1194", "", "2001:db8::1", 1194},
+		{"2001:db8::1:1194", "", "2001:db8::1", 1194},
+		{"udp6:2001:db8::1:1194", "udp6", "2001:db8::1", 1194},
+		{"[AF_INET]1.2.3.4:5555", "", "1.2.3.4", 5555},
+		{"2001:db8::", "", "2001:db8::", 0},
+	}
+
+	for _, tt := range tests {
+		ep, err := ParseEndpoint(tt.input)
+		if err != nil {
+			t.Errorf("ParseEndpoint(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if ep.Transport != tt.transport {
+			t.Errorf("ParseEndpoint(%q) transport = '%s', expected '%s'", tt.input, ep.Transport, tt.transport)
+		}
+		if ep.Addr.String() != tt.addr {
+			t.Errorf("ParseEndpoint(%q) addr = '%s', expected '%s'", tt.input, ep.Addr, tt.addr)
+		}
+		if ep.Port != tt.port {
+			t.Errorf("ParseEndpoint(%q) port = %d, expected %d", tt.input, ep.Port, tt.port)
+		}
+	}
+
+	for _, input := range []string{"", "host.example.com:1194", "1.2.3.4:port"} {
+		if _, err := ParseEndpoint(input); err == nil {
+			t.Errorf("ParseEndpoint(%q) expected error, got none", input)
+		}
+	}
+}
+
+// TestParseFileRealEndpoint tests that real addresses keep the raw string and the parsed endpoint
+func TestParseFileRealEndpoint(t *testing.T) {
+	content := "CLIENT_LIST,user1,udp4:192.168.1.100:54321,10.8.0.2,,1048576,2097152,Thu Nov 27 09:30:45 2025,1732700645,user1,0,0,AES-256-GCM\n" +
+		"ROUTING_TABLE,10.8.0.2,user1,udp4:192.168.1.100:54321,Thu Nov 27 10:30:45 2025,1732704645\n"
+
+	status, errors := Parse(strings.NewReader(content), ParseOptions{Version: Version2})
+
+	if len(errors) > 0 {
+		t.Errorf("Expected no errors, got %d: %v", len(errors), errors)
+	}
+
+	client := status.ClientList[0]
+	if client.RealAddress != "udp4:192.168.1.100:54321" {
+		t.Errorf("Expected raw RealAddress 'udp4:192.168.1.100:54321', got '%s'", client.RealAddress)
+	}
+	if client.RealEndpoint == nil {
+		t.Fatal("Expected RealEndpoint, got nil")
+	}
+	if client.RealEndpoint.Port != 54321 || client.RealEndpoint.Transport != "udp4" {
+		t.Errorf("Expected udp4 port 54321, got %+v", client.RealEndpoint)
+	}
+
+	if status.RoutingTable[0].RealEndpoint == nil {
+		t.Error("Expected route RealEndpoint, got nil")
+	}
+}
+
+// TestParseTime tests parsing of the time formats written by OpenVPN
+func TestParseTime(t *testing.T) {
+	expected := time.Date(2025, time.November, 7, 9, 30, 45, 0, time.UTC)
+
+	inputs := []string{
+		"Fri Nov  7 09:30:45 2025",
+		"Fri Nov 7 09:30:45 2025",
+		"2025-11-07 09:30:45",
+		"2025-11-07T09:30:45Z",
+	}
+	for _, input := range inputs {
+		parsed, err := ParseTime(input, time.UTC)
+		if err != nil {
+			t.Errorf("ParseTime(%q) failed: %v", input, err)
+			continue
+		}
+		if !parsed.Equal(expected) {
+			t.Errorf("ParseTime(%q) = %v, expected %v", input, parsed, expected)
+		}
+	}
+
+	// Times without offset are taken in the given location
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	parsed, err := ParseTime("2025-11-07 11:30:45", loc)
+	if err != nil || !parsed.Equal(expected) {
+		t.Errorf("ParseTime in UTC+2 = %v (%v), expected %v", parsed, err, expected)
+	}
+
+	if _, err := ParseTime("yesterday", time.UTC); err == nil {
+		t.Error("Expected error for unknown time format, got none")
+	}
+}
+
+// TestParseV1TimeBackfill tests that v1 epoch times are derived from the human-readable times
+func TestParseV1TimeBackfill(t *testing.T) {
+	content := `OpenVPN CLIENT LIST
+Updated,Thu Nov 27 10:30:45 2025
+Common Name,Real Address,Bytes Received,Bytes Sent,Connected Since
+user1,192.168.1.100:54321,1048576,2097152,Thu Nov 27 09:30:45 2025
+alice,203.0.113.50:12345,5242880,10485760,sometime
+ROUTING TABLE
+Virtual Address,Common Name,Real Address,Last Ref
+10.8.0.2,user1,192.168.1.100:54321,Thu Nov 27 10:30:40 2025
+END
+`
+
+	status, errs := Parse(strings.NewReader(content), ParseOptions{Version: Version1, Location: time.UTC})
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "field connectedSince") {
+		t.Errorf("Expected 1 connectedSince error for unparsable time, got %v", errs)
+	}
+
+	if status.ClientList[0].ConnectedSinceTime != 1764235845 {
+		t.Errorf("Expected ConnectedSinceTime 1764235845, got %d", status.ClientList[0].ConnectedSinceTime)
+	}
+	if status.ClientList[1].ConnectedSinceTime != 0 {
+		t.Errorf("Expected ConnectedSinceTime 0 for unparsable time, got %d", status.ClientList[1].ConnectedSinceTime)
+	}
+	if status.RoutingTable[0].LastRefTime != 1764239440 {
+		t.Errorf("Expected LastRefTime 1764239440, got %d", status.RoutingTable[0].LastRefTime)
+	}
+}
+
 // TestParseErrorType tests the ParseError type
 func TestParseErrorType(t *testing.T) {
 	err := ParseError{
@@ -285,20 +1009,93 @@ func BenchmarkParseFileV3Small(b *testing.B) {
 	}
 }
 
-// BenchmarkParseFileV3Large benchmarks parsing of large status file
-func BenchmarkParseFileV3Large(b *testing.B) {
-	content := "TITLE\tOpenVPN Server\n"
-	for i := 0; i < 100; i++ {
-		content += "CLIENT_LIST\tuser\t192.168.1.100:54321\t10.8.0.2\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\t0\tAES-256-GCM\n"
+// largeStatusV3 builds a v3 status file with n clients and n routes
+func largeStatusV3(n int) string {
+	var sb strings.Builder
+	sb.WriteString("TITLE\tOpenVPN Server\n")
+	for i := 0; i < n; i++ {
+		sb.WriteString("CLIENT_LIST\tuser\t192.168.1.100:54321\t10.8.0.2\t\t1048576\t2097152\tThu Nov 27 09:30:45 2025\t1732700645\tuser1\t0\t0\tAES-256-GCM\n")
+	}
+	for i := 0; i < n; i++ {
+		sb.WriteString("ROUTING_TABLE\t10.8.0.2\tuser\t192.168.1.100:54321\tThu Nov 27 10:30:45 2025\t1732704645\n")
 	}
+	sb.WriteString("END\n")
+	return sb.String()
+}
 
-	tmpfile, _ := os.CreateTemp("", "benchmark-large-*.log")
-	tmpfile.Write([]byte(content))
-	tmpfile.Close()
-	defer os.Remove(tmpfile.Name())
+// benchmarkSizes are the client counts used by the large file benchmarks
+var benchmarkSizes = []int{100, 1000, 20000}
 
-	b.ResetTimer()
+// measureRetainedHeap runs fn b.N times and reports the heap still held
+// by the result of the last run as "retained-B". Unlike B/op, which counts
+// everything allocated, this shows what a caller has to keep in memory.
+func measureRetainedHeap(b *testing.B, fn func() interface{}) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	var result interface{}
 	for i := 0; i < b.N; i++ {
-		ParseFile(tmpfile.Name(), Version3)
+		result = fn()
+	}
+
+	b.StopTimer()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(result)
+
+	retained := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	if retained < 0 {
+		retained = 0
+	}
+	b.ReportMetric(float64(retained), "retained-B")
+}
+
+// BenchmarkParseFileV3Large benchmarks parsing of large status files.
+// Memory held grows with the number of clients, see BenchmarkParseStreamV3Large.
+func BenchmarkParseFileV3Large(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("clients=%d", n), func(b *testing.B) {
+			tmpfile, _ := os.CreateTemp("", "benchmark-large-*.log")
+			tmpfile.Write([]byte(largeStatusV3(n)))
+			tmpfile.Close()
+			defer os.Remove(tmpfile.Name())
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			measureRetainedHeap(b, func() interface{} {
+				status, _ := ParseFile(tmpfile.Name(), Version3)
+				return status
+			})
+		})
+	}
+}
+
+// BenchmarkParseStreamV3Large benchmarks streaming large status files
+// through a handler that drops every row. Retained memory stays flat
+// regardless of the number of clients.
+func BenchmarkParseStreamV3Large(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("clients=%d", n), func(b *testing.B) {
+			tmpfile, _ := os.CreateTemp("", "benchmark-stream-*.log")
+			tmpfile.Write([]byte(largeStatusV3(n)))
+			tmpfile.Close()
+			defer os.Remove(tmpfile.Name())
+
+			clients := 0
+			handler := HandlerFuncs{Client: func(Client) error {
+				clients++
+				return nil
+			}}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			measureRetainedHeap(b, func() interface{} {
+				file, _ := os.Open(tmpfile.Name())
+				defer file.Close()
+				status, _ := ParseStream(file, ParseOptions{Version: Version3}, handler)
+				return status
+			})
+		})
 	}
 }
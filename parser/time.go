@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the formats OpenVPN uses for human-readable times:
+// ctime-style up to 2.5 and ISO 8601 from 2.6 on.
+var timeLayouts = []string{
+	"Mon Jan _2 15:04:05 2006",
+	"Mon Jan 2 15:04:05 2006",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+// ParseTime parses a human-readable time from a status file, such as
+// "Thu Nov 27 09:30:45 2025" or "2025-11-27 09:30:45".
+// OpenVPN writes these in the server's local time zone without an offset,
+// they are interpreted in loc (time.Local if nil).
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format")
+}
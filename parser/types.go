@@ -1,11 +1,18 @@
 package parser
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // StatusVersion represents the OpenVPN status file version
 type StatusVersion int
 
 const (
+	// VersionAuto - Detect the version from the file contents
+	VersionAuto StatusVersion = 0
 	// Version1 - Traditional format with comma-separated basic fields
 	Version1 StatusVersion = 1
 	// Version2 - Extended format with comma-separated fields including virtual IPs, username, etc.
@@ -19,18 +26,55 @@ const (
 type Status struct {
 	Server *ServerConfig `json:"server,omitempty"`
 
+	// Version is the status file version the file was parsed as.
+	// With VersionAuto it is the detected version, or VersionAuto if the
+	// file had no content to detect it from.
+	Version StatusVersion `json:"version,omitempty"`
+
 	// Title contains the OpenVPN server title/description (v2/v3 only)
 	Title string `json:"title,omitempty"`
 
-	// Time contains timestamp information from the status file (v2/v3 only)
-	// Usually includes human-readable time and epoch time
+	// Time contains timestamp information from the status file.
+	// v2/v3 include human-readable time and epoch time, v1 only the
+	// human-readable time from the "Updated" line
 	Time []string `json:"time,omitempty"`
 
 	// ClientList contains all connected clients
 	ClientList []Client `json:"clientList"`
 
-	// RoutingTable contains virtual IP to client mappings (v2/v3 only)
+	// RoutingTable contains virtual IP to client mappings
 	RoutingTable []Route `json:"routingTable,omitempty"`
+
+	// Statistics contains the byte counters of a client mode status
+	// file, nil for server status files
+	Statistics *ClientStatistics `json:"statistics,omitempty"`
+
+	// GlobalStats contains server-wide statistics, nil if the file has none
+	GlobalStats *GlobalStats `json:"globalStats,omitempty"`
+
+	// Complete is true if the END marker was found, i.e. the file was
+	// not caught while OpenVPN was rewriting it
+	Complete bool `json:"complete"`
+
+	// ErrorCounts is the number of parse errors per field, nil if there
+	// were none
+	ErrorCounts map[string]int `json:"errorCounts,omitempty"`
+
+	// Unknown contains records of types not known to this parser (v2/v3 only),
+	// kept so that additions to the format are not lost
+	Unknown []UnknownRecord `json:"unknown,omitempty"`
+}
+
+// UnknownRecord is a status file line with an unrecognized record type.
+type UnknownRecord struct {
+	// Type is the record type, the first field of the line
+	Type string `json:"type"`
+
+	// Fields are the remaining fields of the line
+	Fields []string `json:"fields"`
+
+	// Line is the line number (1-indexed)
+	Line int `json:"line"`
 }
 
 type ServerConfig struct {
@@ -50,19 +94,173 @@ type ServerConfig struct {
 
 	// --dev
 	Dev string `json:"dev,omitempty"`
+
+	// Management holds what the management interface reports about the
+	// server, nil if the status was not read from it
+	Management *ManagementInfo `json:"management,omitempty"`
+
+	// Log holds what the OpenVPN log file reports about logins and
+	// disconnects, nil if no log file was read
+	Log *LogStats `json:"log,omitempty"`
+
+	// Pool holds the addresses reserved per common name in the
+	// ifconfig-pool-persist file, nil if it was not read
+	Pool *PoolInfo `json:"pool,omitempty"`
+
+	// CRL describes the certificate revocation list checked against the
+	// connected clients, nil if none was read
+	CRL *CRLInfo `json:"crl,omitempty"`
+
+	// CCD describes the client-config-dir checked against the connected
+	// clients and routes, nil if it was not read
+	CCD *CCDInfo `json:"ccd,omitempty"`
+}
+
+// ManagementInfo is the server state reported by the management interface
+// commands load-stats, state and version.
+type ManagementInfo struct {
+	// Clients is "nclients" from load-stats
+	Clients int64 `json:"clients"`
+
+	// BytesIn is "bytesin" from load-stats, bytes received from all clients
+	BytesIn int64 `json:"bytesIn"`
+
+	// BytesOut is "bytesout" from load-stats, bytes sent to all clients
+	BytesOut int64 `json:"bytesOut"`
+
+	// State is the current state from state, e.g. CONNECTED or RECONNECTING
+	State string `json:"state,omitempty"`
+
+	// StateTime is the Unix time the server entered State
+	StateTime int64 `json:"stateTime,omitempty"`
+
+	// LocalIP is the server's tunnel address from state
+	LocalIP string `json:"localIp,omitempty"`
+
+	// OpenVPNVersion is the "OpenVPN Version" line from version,
+	// e.g. "OpenVPN 2.6.8 x86_64-pc-linux-gnu [SSL (OpenSSL)] ..."
+	OpenVPNVersion string `json:"openvpnVersion,omitempty"`
+
+	// ManagementVersion is the "Management Version" from version
+	ManagementVersion string `json:"managementVersion,omitempty"`
+}
+
+// LogStats counts the connection lifecycle events of an OpenVPN log file,
+// see package logparser.
+type LogStats struct {
+	// Connections is the number of "Peer Connection Initiated" lines
+	Connections int64 `json:"connections"`
+
+	// AuthFailures is the number of AUTH_FAILED lines per common name,
+	// "" if the log line does not name the client
+	AuthFailures map[string]int64 `json:"authFailures,omitempty"`
+
+	// TLSErrors is the number of "TLS Error:" lines
+	TLSErrors int64 `json:"tlsErrors"`
+
+	// Disconnects is the number of client instances exiting or restarting
+	// per reason, e.g. "remote-exit" or "ping-restart"
+	Disconnects map[string]int64 `json:"disconnects,omitempty"`
+
+	// InactivityTimeouts is the number of "Inactivity timeout" lines
+	InactivityTimeouts int64 `json:"inactivityTimeouts"`
+}
+
+// PoolInfo is the content of an ifconfig-pool-persist file, see package ipp.
+type PoolInfo struct {
+	// Entries are the persisted addresses in file order
+	Entries []PoolEntry `json:"entries"`
+
+	// Conflicts are addresses persisted for more than one common name
+	Conflicts []PoolConflict `json:"conflicts,omitempty"`
+}
+
+// PoolEntry is a line of the ifconfig-pool-persist file.
+type PoolEntry struct {
+	// CommonName is the client the addresses are reserved for
+	CommonName string `json:"commonName"`
+
+	// Address is the persisted IPv4 address, empty if none
+	Address string `json:"address,omitempty"`
+
+	// IPv6Address is the persisted IPv6 address (OpenVPN 2.5+), empty if none
+	IPv6Address string `json:"ipv6Address,omitempty"`
+
+	// Line is the line number (1-indexed)
+	Line int `json:"line"`
+}
+
+// PoolConflict is an address persisted for several common names.
+type PoolConflict struct {
+	// Address is the IPv4 or IPv6 address
+	Address string `json:"address"`
+
+	// CommonNames are the clients the address is persisted for, in file order
+	CommonNames []string `json:"commonNames"`
+}
+
+// CRLInfo describes a certificate revocation list, see package pki.
+type CRLInfo struct {
+	// ThisUpdate is the Unix time the CRL was issued, 0 for a crl-verify
+	// directory
+	ThisUpdate int64 `json:"thisUpdate,omitempty"`
+
+	// NextUpdate is the Unix time by which the next CRL will be issued,
+	// 0 if not set
+	NextUpdate int64 `json:"nextUpdate,omitempty"`
+
+	// Revoked is the number of revoked certificates in the CRL
+	Revoked int `json:"revoked"`
+
+	// Stale is true if NextUpdate has passed, i.e. the CRL should have
+	// been replaced by a newer one
+	Stale bool `json:"stale"`
+
+	// MissingRevocations is the number of unexpired certificates revoked
+	// in index.txt but not in the CRL, i.e. the CRL was not regenerated
+	// after revoking them
+	MissingRevocations int `json:"missingRevocations"`
+
+	// RevokedConnected is the number of connected clients whose
+	// certificate is revoked
+	RevokedConnected int `json:"revokedConnected"`
+}
+
+// CCDInfo describes a client-config-dir, see package ccd.
+type CCDInfo struct {
+	// Files is the number of client config files read
+	Files int `json:"files"`
+
+	// MissingIRoutes are iroutes of connected clients that are not in
+	// the routing table
+	MissingIRoutes []MissingIRoute `json:"missingIroutes,omitempty"`
+}
+
+// MissingIRoute is an iroute of a connected client not in the routing table.
+type MissingIRoute struct {
+	// CommonName is the client the iroute is configured for
+	CommonName string `json:"commonName"`
+
+	// Network is the iroute in CIDR notation
+	Network string `json:"network"`
 }
 
 // Client represents a single connected OpenVPN client.
 // Fields availability depends on status file version:
-// - v1: CommonName, RealAddress, BytesReceived, BytesSent, ConnectedSince
+// - v1: CommonName, RealAddress, BytesReceived, BytesSent, ConnectedSince, ConnectedSinceTime
 // - v2/v3: All fields
 type Client struct {
 	// CommonName is the client's certificate common name (CN) - all versions
 	CommonName string `json:"commonName"`
 
-	// RealAddress is the client's actual IP:port (e.g., "1.2.3.4:12345") - all versions
+	// RealAddress is the client's actual address as written in the status
+	// file (e.g., "1.2.3.4:12345", "udp4:1.2.3.4:12345") - all versions
 	RealAddress string `json:"realAddress"`
 
+	// RealEndpoint is RealAddress parsed into transport, IP and port,
+	// nil if it could not be parsed - all versions
+	RealEndpoint *Endpoint `json:"realEndpoint,omitempty"`
+
 	// VirtualAddress is the assigned VPN IP (e.g., "10.8.0.2") - v2/v3 only
 	VirtualAddress string `json:"virtualAddress,omitempty"`
 
@@ -78,7 +276,8 @@ type Client struct {
 	// ConnectedSince is human-readable connection start time - all versions
 	ConnectedSince string `json:"connectedSince"`
 
-	// ConnectedSinceTime is Unix timestamp when client connected - v2/v3 only
+	// ConnectedSinceTime is Unix timestamp when client connected - all versions,
+	// derived from ConnectedSince for v1 which has no epoch column
 	ConnectedSinceTime int64 `json:"connectedSinceTime,omitempty"`
 
 	// Username is the authenticated username (optional) - v2/v3 only
@@ -92,10 +291,49 @@ type Client struct {
 
 	// DataCipher is the data channel cipher - v2/v3 only (optional field)
 	DataCipher string `json:"dataCipher,omitempty"`
+
+	// Extra contains values of header columns not known to this parser,
+	// keyed by column name
+	Extra map[string]string `json:"extra,omitempty"`
+
+	// PersistedAddress is the IPv4 address reserved for the common name in
+	// the ifconfig-pool-persist file, empty if unknown
+	PersistedAddress string `json:"persistedAddress,omitempty"`
+
+	// PersistedIPv6Address is the reserved IPv6 address, empty if unknown
+	PersistedIPv6Address string `json:"persistedIPv6Address,omitempty"`
+
+	// AddressMismatch is true if VirtualAddress or VirtualIPv6Address
+	// differs from the persisted address
+	AddressMismatch bool `json:"addressMismatch,omitempty"`
+
+	// CertNotAfter is the Unix time the client's certificate expires,
+	// 0 if the certificate is unknown
+	CertNotAfter int64 `json:"certNotAfter,omitempty"`
+
+	// CertSerial is the certificate serial number in upper case hex
+	CertSerial string `json:"certSerial,omitempty"`
+
+	// CertRevoked is true if the certificate has been revoked
+	CertRevoked bool `json:"certRevoked,omitempty"`
+
+	// StaticAddress is the IPv4 address from ifconfig-push in the
+	// client's client-config-dir file, empty if none
+	StaticAddress string `json:"staticAddress,omitempty"`
+
+	// StaticIPv6Address is the address from ifconfig-ipv6-push, empty if none
+	StaticIPv6Address string `json:"staticIPv6Address,omitempty"`
+
+	// StaticMismatch is true if VirtualAddress or VirtualIPv6Address
+	// differs from the static address
+	StaticMismatch bool `json:"staticMismatch,omitempty"`
+
+	// IRoutes are the iroute and iroute-ipv6 networks of the client's
+	// client-config-dir file in CIDR notation
+	IRoutes []string `json:"iroutes,omitempty"`
 }
 
 // Route represents a single routing table entry.
-// Only available in v2/v3 formats.
 type Route struct {
 	// VirtualAddress is the routed VPN IP or network
 	VirtualAddress string `json:"virtualAddress"`
@@ -103,14 +341,70 @@ type Route struct {
 	// CommonName is the client certificate CN this route points to
 	CommonName string `json:"commonName"`
 
-	// RealAddress is the client's actual IP:port
+	// RealAddress is the client's actual address as written in the status file
 	RealAddress string `json:"realAddress"`
 
+	// RealEndpoint is RealAddress parsed into transport, IP and port,
+	// nil if it could not be parsed
+	RealEndpoint *Endpoint `json:"realEndpoint,omitempty"`
+
 	// LastRef is human-readable time of last routing table update
 	LastRef string `json:"lastRef"`
 
-	// LastRefTime is Unix timestamp of last routing table update
+	// LastRefTime is Unix timestamp of last routing table update - all versions,
+	// derived from LastRef for v1 which has no epoch column
 	LastRefTime int64 `json:"lastRefTime"`
+
+	// Extra contains values of header columns not known to this parser,
+	// keyed by column name
+	Extra map[string]string `json:"extra,omitempty"`
+
+	// CCDDirective is the client-config-dir directive of the client the
+	// route belongs to, "ifconfig-push" or "iroute", empty if the route
+	// is not configured there
+	CCDDirective string `json:"ccdDirective,omitempty"`
+}
+
+// GlobalStats represents the GLOBAL_STATS lines (v2/v3) or the
+// GLOBAL STATS section (v1) of the status file.
+type GlobalStats struct {
+	// MaxBcastMcastQueueLength is "Max bcast/mcast queue length" - all versions
+	MaxBcastMcastQueueLength int64 `json:"maxBcastMcastQueueLength"`
+
+	// DCOEnabled is "dco_enabled" - OpenVPN 2.6+ only, nil if not reported
+	DCOEnabled *bool `json:"dcoEnabled,omitempty"`
+
+	// Extra contains stats not known to this parser, keyed by their name
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// ClientStatistics represents the status file written by OpenVPN in
+// client mode, which has byte counters instead of a client list:
+//
+//	OpenVPN STATISTICS
+//	Updated,Thu Nov 27 10:30:45 2025
+//	TUN/TAP read bytes,1048576
+//	...
+//	END
+type ClientStatistics struct {
+	// TunTapReadBytes is "TUN/TAP read bytes", read from the tunnel device
+	TunTapReadBytes int64 `json:"tunTapReadBytes"`
+
+	// TunTapWriteBytes is "TUN/TAP write bytes", written to the tunnel device
+	TunTapWriteBytes int64 `json:"tunTapWriteBytes"`
+
+	// LinkReadBytes is "TCP/UDP read bytes", received from the server
+	LinkReadBytes int64 `json:"linkReadBytes"`
+
+	// LinkWriteBytes is "TCP/UDP write bytes", sent to the server
+	LinkWriteBytes int64 `json:"linkWriteBytes"`
+
+	// AuthReadBytes is "Auth read bytes", authenticated packet payload read
+	AuthReadBytes int64 `json:"authReadBytes"`
+
+	// Extra contains counters not known to this parser, e.g. the
+	// compression counters, keyed by their name
+	Extra map[string]string `json:"extra,omitempty"`
 }
 
 // ParseError represents an error encountered during parsing.
@@ -125,6 +419,9 @@ type ParseError struct {
 	// Value is the problematic value we tried to parse
 	Value string
 
+	// Kind classifies the error, see the Err* sentinels
+	Kind ErrorKind
+
 	// Err is the underlying error
 	Err error
 }
@@ -133,3 +430,162 @@ type ParseError struct {
 func (e ParseError) Error() string {
 	return fmt.Sprintf("line %d, field %s, value %q: %v", e.Line, e.Field, e.Value, e.Err)
 }
+
+// Unwrap returns the underlying error for errors.Is and errors.As
+func (e ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel error of e's Kind,
+// so that errors.Is(err, ErrBadNumber) works on a ParseError.
+func (e ParseError) Is(target error) bool {
+	sentinel, ok := kindErrors[e.Kind]
+	return ok && target == sentinel
+}
+
+// ErrorKind classifies a ParseError
+type ErrorKind int
+
+const (
+	// KindOther - Anything not covered by the kinds below
+	KindOther ErrorKind = iota
+
+	// KindShortRecord - A line has fewer fields than its record type needs
+	KindShortRecord
+
+	// KindBadNumber - A numeric field is not a valid integer
+	KindBadNumber
+
+	// KindBadValue - An address, time or flag field cannot be parsed
+	KindBadValue
+
+	// KindVersionMismatch - The file does not look like the requested version
+	KindVersionMismatch
+
+	// KindTruncated - The file ends without END marker
+	KindTruncated
+
+	// KindInconsistent - A strict mode check failed, e.g. a duplicate client ID
+	KindInconsistent
+)
+
+// Sentinel errors for use with errors.Is.
+var (
+	// ErrFileNotFound is wrapped by the error returned when the status file does not exist
+	ErrFileNotFound = errors.New("status file not found")
+
+	// ErrShortRecord matches ParseErrors of KindShortRecord
+	ErrShortRecord = errors.New("short record")
+
+	// ErrBadNumber matches ParseErrors of KindBadNumber
+	ErrBadNumber = errors.New("bad number")
+
+	// ErrVersionMismatch matches ParseErrors of KindVersionMismatch
+	ErrVersionMismatch = errors.New("status version mismatch")
+
+	// ErrTruncated matches ParseErrors of KindTruncated and is wrapped by
+	// the error returned when retries of a torn read are exhausted
+	ErrTruncated = errors.New("status file truncated")
+)
+
+// kindErrors maps error kinds to their sentinel error
+var kindErrors = map[ErrorKind]error{
+	KindShortRecord:     ErrShortRecord,
+	KindBadNumber:       ErrBadNumber,
+	KindVersionMismatch: ErrVersionMismatch,
+	KindTruncated:       ErrTruncated,
+}
+
+// RetryNotice reports a torn read of the status file that was retried.
+// It is informational: a later attempt may have read the file completely.
+// If all retries fail, an error wrapping ErrTruncated follows instead.
+type RetryNotice struct {
+	// Reason describes why the snapshot looked torn
+	Reason string
+
+	// Retry is the number of the retry about to be made, from 1
+	Retry int
+
+	// Retries is the maximum number of retries
+	Retries int
+
+	// Backoff is the delay before the retry
+	Backoff time.Duration
+}
+
+// Error implements the error interface
+func (n RetryNotice) Error() string {
+	return fmt.Sprintf("torn read (%s), retrying in %v (retry %d of %d)", n.Reason, n.Backoff, n.Retry, n.Retries)
+}
+
+// SplitRetryNotices separates the RetryNotice values in errs from the
+// actual errors.
+func SplitRetryNotices(errs []error) (notices []RetryNotice, rest []error) {
+	for _, err := range errs {
+		if n, ok := err.(RetryNotice); ok {
+			notices = append(notices, n)
+			continue
+		}
+		rest = append(rest, err)
+	}
+	return notices, rest
+}
+
+// ParseErrors holds several ParseError values, e.g. all invalid fields of one line.
+// errors.Is and errors.As look at every contained ParseError.
+type ParseErrors []ParseError
+
+// Error implements the error interface, joining the messages with "; "
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the contained errors for errors.Is and errors.As
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Summary counts the errors by field
+func (e ParseErrors) Summary() map[string]int {
+	counts := make(map[string]int)
+	for _, err := range e {
+		counts[err.Field]++
+	}
+	return counts
+}
+
+// err returns nil for no errors, the ParseError itself for a single one
+// and the whole list otherwise.
+func (e ParseErrors) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	default:
+		return e
+	}
+}
+
+// FlattenErrors collects the ParseError values in errs, unpacking
+// ParseErrors lists. Other errors, e.g. read errors, are skipped.
+func FlattenErrors(errs []error) ParseErrors {
+	var flat ParseErrors
+	for _, err := range errs {
+		switch e := err.(type) {
+		case ParseError:
+			flat = append(flat, e)
+		case ParseErrors:
+			flat = append(flat, e...)
+		}
+	}
+	return flat
+}
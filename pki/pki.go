@@ -0,0 +1,493 @@
+// Package pki reads the certificate inventory of a certificate authority,
+// the OpenSSL index.txt maintained by easy-rsa and the issued certificates,
+// to tell when the certificates of connected clients expire and whether
+// they have been revoked, also according to the CRL the server checks.
+package pki
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"openvpn-status-parser/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Certificate is a client certificate of the inventory.
+type Certificate struct {
+	// CommonName is the CN of the certificate subject
+	CommonName string `json:"commonName"`
+
+	// Serial is the serial number in upper case hex without leading zeros
+	Serial string `json:"serial"`
+
+	// NotAfter is when the certificate expires
+	NotAfter time.Time `json:"notAfter"`
+
+	// Revoked is true if the certificate has been revoked
+	Revoked bool `json:"revoked,omitempty"`
+
+	// RevokedAt is when the certificate was revoked, zero if not revoked
+	// or unknown
+	RevokedAt time.Time `json:"revokedAt,omitempty"`
+}
+
+// Inventory holds the known certificates by common name.
+type Inventory struct {
+	byCommonName map[string][]*Certificate
+	bySerial     map[string]*Certificate
+}
+
+// NewInventory creates an empty inventory.
+func NewInventory() *Inventory {
+	return &Inventory{
+		byCommonName: make(map[string][]*Certificate),
+		bySerial:     make(map[string]*Certificate),
+	}
+}
+
+// Len returns the number of certificates in the inventory
+func (inv *Inventory) Len() int {
+	return len(inv.bySerial)
+}
+
+// add adds cert to the inventory. A certificate already known by serial is
+// updated instead: revocation is kept, the expiry is taken from cert.
+func (inv *Inventory) add(cert Certificate) {
+	if known, ok := inv.bySerial[cert.Serial]; ok {
+		known.NotAfter = cert.NotAfter
+		if known.CommonName == "" && cert.CommonName != "" {
+			known.CommonName = cert.CommonName
+			inv.byCommonName[cert.CommonName] = append(inv.byCommonName[cert.CommonName], known)
+		}
+		if cert.Revoked {
+			known.Revoked = true
+			known.RevokedAt = cert.RevokedAt
+		}
+		return
+	}
+
+	c := cert
+	inv.bySerial[c.Serial] = &c
+	inv.byCommonName[c.CommonName] = append(inv.byCommonName[c.CommonName], &c)
+}
+
+// ReadIndex adds the certificates of the OpenSSL index.txt at path, see
+// ParseIndex.
+func (inv *Inventory) ReadIndex(path string) []error {
+	file, err := os.Open(path)
+	if err != nil {
+		return []error{fmt.Errorf("failed to open certificate index: %w", err)}
+	}
+	defer file.Close()
+
+	return inv.ParseIndex(file)
+}
+
+// ParseIndex adds the certificates of an OpenSSL index.txt as written by
+// easy-rsa. Lines have tab-separated fields:
+//
+//	V|R|E  expiry  [revocation[,reason]]  serial  filename  subject
+//
+// Invalid lines are skipped and returned as ParseErrors.
+func (inv *Inventory) ParseIndex(r io.Reader) []error {
+	var parseErrors []error
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		cert, err := parseIndexLine(line, lineNum)
+		if err != nil {
+			parseErrors = append(parseErrors, err)
+			continue
+		}
+		inv.add(cert)
+	}
+	if err := scanner.Err(); err != nil {
+		parseErrors = append(parseErrors, fmt.Errorf("error reading certificate index: %w", err))
+	}
+	return parseErrors
+}
+
+// parseIndexLine parses a line of index.txt
+func parseIndexLine(line string, lineNum int) (Certificate, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 6 {
+		return Certificate{}, parser.ParseError{
+			Line: lineNum, Field: "record", Value: line, Kind: parser.KindShortRecord,
+			Err: fmt.Errorf("expected 6 tab-separated fields, got %d", len(fields)),
+		}
+	}
+
+	var errs parser.ParseErrors
+	cert := Certificate{
+		Serial:     normalizeSerial(fields[3]),
+		CommonName: subjectCommonName(fields[5]),
+		Revoked:    fields[0] == "R",
+	}
+
+	switch fields[0] {
+	case "V", "R", "E":
+	default:
+		errs = append(errs, parser.ParseError{
+			Line: lineNum, Field: "status", Value: fields[0], Kind: parser.KindBadValue,
+			Err: fmt.Errorf("expected V, R or E"),
+		})
+	}
+
+	notAfter, err := parseASN1Time(fields[1])
+	if err != nil {
+		errs = append(errs, parser.ParseError{
+			Line: lineNum, Field: "expiry", Value: fields[1], Kind: parser.KindBadValue, Err: err,
+		})
+	}
+	cert.NotAfter = notAfter
+
+	if cert.Revoked {
+		revokedAt, _, _ := strings.Cut(fields[2], ",")
+		t, err := parseASN1Time(revokedAt)
+		if err != nil {
+			errs = append(errs, parser.ParseError{
+				Line: lineNum, Field: "revocation", Value: fields[2], Kind: parser.KindBadValue, Err: err,
+			})
+		}
+		cert.RevokedAt = t
+	}
+
+	if cert.Serial == "" {
+		errs = append(errs, parser.ParseError{
+			Line: lineNum, Field: "serial", Value: fields[3], Kind: parser.KindBadValue,
+			Err: fmt.Errorf("invalid serial number"),
+		})
+	}
+
+	if len(errs) > 0 {
+		return cert, errs
+	}
+	return cert, nil
+}
+
+// ReadCertDir adds the PEM certificates (*.crt and *.pem) in dir, e.g.
+// easy-rsa's pki/issued. Files that cannot be read or parsed are skipped
+// and returned as errors.
+func (inv *Inventory) ReadCertDir(dir string) []error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read certificate directory: %w", err)}
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".crt" && ext != ".pem") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		cert, err := readCertFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		inv.add(cert)
+	}
+	return errs
+}
+
+// readCertFile reads the first certificate of a PEM file
+func readCertFile(path string) (Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Certificate{}, err
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return Certificate{}, fmt.Errorf("no PEM certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		x, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return Certificate{}, err
+		}
+		return Certificate{
+			CommonName: x.Subject.CommonName,
+			Serial:     formatSerial(x.SerialNumber),
+			NotAfter:   x.NotAfter,
+		}, nil
+	}
+}
+
+// Lookup returns the certificate a client with common name cn is most
+// likely using: the valid certificate expiring last, or if all have been
+// revoked, the one expiring last. ok is false if cn is unknown.
+func (inv *Inventory) Lookup(cn string) (cert Certificate, ok bool) {
+	var best *Certificate
+	for _, c := range inv.byCommonName[cn] {
+		switch {
+		case best == nil:
+			best = c
+		case best.Revoked && !c.Revoked:
+			best = c
+		case best.Revoked == c.Revoked && c.NotAfter.After(best.NotAfter):
+			best = c
+		}
+	}
+	if best == nil {
+		return Certificate{}, false
+	}
+	return *best, true
+}
+
+// Annotate sets CertNotAfter, CertSerial and CertRevoked of client from
+// its certificate, see Lookup. Unknown clients are left unchanged.
+func (inv *Inventory) Annotate(client *parser.Client) {
+	cert, ok := inv.Lookup(client.CommonName)
+	if !ok {
+		return
+	}
+	client.CertNotAfter = cert.NotAfter.Unix()
+	client.CertSerial = cert.Serial
+	client.CertRevoked = cert.Revoked
+}
+
+// parseASN1Time parses an ASN.1 UTCTime (YYMMDDHHMMSSZ) or GeneralizedTime
+// (YYYYMMDDHHMMSSZ) as used in index.txt. Two-digit years from 50 on are
+// 19xx, as in X.509.
+func parseASN1Time(s string) (time.Time, error) {
+	if len(s) == len("060102150405Z") {
+		century := "20"
+		if s[:2] >= "50" {
+			century = "19"
+		}
+		s = century + s
+	}
+	t, err := time.Parse("20060102150405Z", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time, expected YYMMDDHHMMSSZ")
+	}
+	return t, nil
+}
+
+// normalizeSerial brings a hex serial number into the form used by
+// formatSerial, "" if s is not hex.
+func normalizeSerial(s string) string {
+	n, ok := new(big.Int).SetString(strings.TrimSpace(s), 16)
+	if !ok {
+		return ""
+	}
+	return formatSerial(n)
+}
+
+// formatSerial formats a serial number as upper case hex
+func formatSerial(n *big.Int) string {
+	return strings.ToUpper(n.Text(16))
+}
+
+// subjectCommonName returns the CN of a subject in OpenSSL's one-line
+// form, e.g. "/C=DE/O=Example/CN=user1", "" if there is none.
+func subjectCommonName(subject string) string {
+	var cn string
+	for _, part := range strings.Split(subject, "/") {
+		if value, ok := strings.CutPrefix(part, "CN="); ok {
+			cn = value
+		}
+	}
+	return cn
+}
+
+// CRL is a certificate revocation list as used by the crl-verify directive.
+type CRL struct {
+	// ThisUpdate is when the CRL was issued, zero for a directory
+	ThisUpdate time.Time
+
+	// NextUpdate is when the next CRL will be issued, zero if not set
+	NextUpdate time.Time
+
+	// revoked are the revocation times by serial
+	revoked map[string]time.Time
+}
+
+// ReadCRL reads a CRL file in PEM or DER form, or a directory of files
+// named by the decimal serial numbers of revoked certificates, as used
+// by "crl-verify <dir> dir".
+func ReadCRL(path string) (*CRL, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CRL: %w", err)
+	}
+	if info.IsDir() {
+		return readCRLDir(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRL: %w", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	list, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %w", err)
+	}
+
+	crl := &CRL{
+		ThisUpdate: list.ThisUpdate,
+		NextUpdate: list.NextUpdate,
+		revoked:    make(map[string]time.Time, len(list.RevokedCertificateEntries)),
+	}
+	for _, entry := range list.RevokedCertificateEntries {
+		crl.revoked[formatSerial(entry.SerialNumber)] = entry.RevocationTime
+	}
+	return crl, nil
+}
+
+// readCRLDir reads a crl-verify directory. Files not named by a decimal
+// number are ignored.
+func readCRLDir(dir string) (*CRL, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRL directory: %w", err)
+	}
+
+	crl := &CRL{revoked: make(map[string]time.Time)}
+	for _, entry := range entries {
+		if n, ok := new(big.Int).SetString(entry.Name(), 10); ok && !entry.IsDir() {
+			crl.revoked[formatSerial(n)] = time.Time{}
+		}
+	}
+	return crl, nil
+}
+
+// Len returns the number of revoked certificates
+func (c *CRL) Len() int {
+	return len(c.revoked)
+}
+
+// IsRevoked reports whether the certificate with serial (upper case hex,
+// see Certificate.Serial) is revoked
+func (c *CRL) IsRevoked(serial string) bool {
+	_, ok := c.revoked[serial]
+	return ok
+}
+
+// ApplyCRL marks the certificates revoked by crl as revoked, so that
+// Annotate reports clients using them, and describes crl at time now.
+func (inv *Inventory) ApplyCRL(crl *CRL, now time.Time) *parser.CRLInfo {
+	info := &parser.CRLInfo{
+		Revoked: crl.Len(),
+		Stale:   !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate),
+	}
+	if !crl.ThisUpdate.IsZero() {
+		info.ThisUpdate = crl.ThisUpdate.Unix()
+	}
+	if !crl.NextUpdate.IsZero() {
+		info.NextUpdate = crl.NextUpdate.Unix()
+	}
+
+	for serial, cert := range inv.bySerial {
+		revokedAt, inCRL := crl.revoked[serial]
+		switch {
+		case inCRL && !cert.Revoked:
+			cert.Revoked = true
+			cert.RevokedAt = revokedAt
+		case !inCRL && cert.Revoked && now.Before(cert.NotAfter):
+			info.MissingRevocations++
+		}
+	}
+	return info
+}
+
+// RevokedSession is a connected client that may be using a certificate
+// revoked by the CRL
+type RevokedSession struct {
+	// CommonName is the common name of the client
+	CommonName string
+
+	// RealAddress is the address the client connected from
+	RealAddress string
+
+	// Serial is the revoked certificate
+	Serial string
+}
+
+// CRLChecker checks clients against a CRL as they are parsed and counts
+// the ones that may be connected with a revoked certificate.
+type CRLChecker struct {
+	inv  *Inventory
+	crl  *CRL
+	info *parser.CRLInfo
+
+	// sessions are the clients flagged so far
+	sessions []RevokedSession
+}
+
+// NewCRLChecker applies crl to inv at time now, see ApplyCRL, and creates
+// a checker for it.
+func NewCRLChecker(inv *Inventory, crl *CRL, now time.Time) *CRLChecker {
+	return &CRLChecker{inv: inv, crl: crl, info: inv.ApplyCRL(crl, now)}
+}
+
+// Info returns the summary the checker fills in; RevokedConnected counts
+// the clients annotated so far.
+func (c *CRLChecker) Info() *parser.CRLInfo {
+	return c.info
+}
+
+// Sessions returns the clients annotated so far that may be connected with
+// a revoked certificate, in the order they were seen.
+func (c *CRLChecker) Sessions() []RevokedSession {
+	return c.sessions
+}
+
+// AnnotateClient checks client against the CRL. The status file does not
+// tell which certificate a client uses, so the client is flagged if any
+// certificate of its common name is in the CRL, also when it has a renewed
+// certificate; only certificates that had expired when it connected are
+// ruled out. A flagged client gets CertNotAfter, CertSerial and CertRevoked
+// from the revoked certificate expiring last.
+func (c *CRLChecker) AnnotateClient(client *parser.Client) {
+	var revoked *Certificate
+	for _, cert := range c.inv.byCommonName[client.CommonName] {
+		if !c.crl.IsRevoked(cert.Serial) {
+			continue
+		}
+		if client.ConnectedSinceTime > 0 && cert.NotAfter.Unix() < client.ConnectedSinceTime {
+			continue
+		}
+		if revoked == nil || cert.NotAfter.After(revoked.NotAfter) {
+			revoked = cert
+		}
+	}
+	if revoked == nil {
+		return
+	}
+
+	client.CertNotAfter = revoked.NotAfter.Unix()
+	client.CertSerial = revoked.Serial
+	client.CertRevoked = true
+	c.info.RevokedConnected++
+	c.sessions = append(c.sessions, RevokedSession{
+		CommonName:  client.CommonName,
+		RealAddress: client.RealAddress,
+		Serial:      revoked.Serial,
+	})
+}
@@ -0,0 +1,310 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"openvpn-status-parser/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIndex has a renewed certificate for user1, a revoked one for user2
+// and a certificate with a long subject for user3
+const testIndex = "R\t250101000000Z\t240601120000Z,keyCompromise\t01\tunknown\t/CN=user1\n" +
+	"V\t271127103045Z\t\t0A\tunknown\t/CN=user1\n" +
+	"R\t271127103045Z\t251120080000Z\t0B\tunknown\t/CN=user2\n" +
+	"V\t20500101000000Z\t\t00FF\tunknown\t/C=DE/O=Example/CN=user3\n"
+
+// TestParseIndex tests reading index.txt and picking the current certificate
+func TestParseIndex(t *testing.T) {
+	inv := NewInventory()
+	if errs := inv.ParseIndex(strings.NewReader(testIndex)); len(errs) > 0 {
+		t.Fatalf("ParseIndex failed: %v", errs)
+	}
+	if inv.Len() != 4 {
+		t.Errorf("Expected 4 certificates, got %d", inv.Len())
+	}
+
+	cert, ok := inv.Lookup("user1")
+	if !ok || cert.Serial != "A" || cert.Revoked {
+		t.Errorf("Expected valid certificate A for user1, got %+v", cert)
+	}
+	if !cert.NotAfter.Equal(time.Date(2027, 11, 27, 10, 30, 45, 0, time.UTC)) {
+		t.Errorf("Expected NotAfter 2027-11-27 10:30:45, got %v", cert.NotAfter)
+	}
+
+	cert, ok = inv.Lookup("user2")
+	if !ok || !cert.Revoked || !cert.RevokedAt.Equal(time.Date(2025, 11, 20, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected user2 revoked at 2025-11-20 08:00, got %+v", cert)
+	}
+
+	cert, ok = inv.Lookup("user3")
+	if !ok || cert.Serial != "FF" || cert.NotAfter.Year() != 2050 {
+		t.Errorf("Expected certificate FF until 2050 for user3, got %+v", cert)
+	}
+
+	if _, ok := inv.Lookup("user4"); ok {
+		t.Error("Expected no certificate for user4")
+	}
+}
+
+// TestParseIndexErrors tests that invalid lines are reported and skipped
+func TestParseIndexErrors(t *testing.T) {
+	content := "V\t271127103045Z\t\t0A\tunknown\n" +
+		"X\t271127103045Z\t\t0B\tunknown\t/CN=user2\n" +
+		"V\t2027-11-27\t\t0C\tunknown\t/CN=user3\n" +
+		"V\t271127103045Z\t\tZZ\tunknown\t/CN=user4\n" +
+		"V\t271127103045Z\t\t0E\tunknown\t/CN=user5\n"
+
+	inv := NewInventory()
+	flat := parser.FlattenErrors(inv.ParseIndex(strings.NewReader(content)))
+	if len(flat) != 4 {
+		t.Fatalf("Expected 4 errors, got %d: %v", len(flat), flat)
+	}
+	if !errors.Is(flat[0], parser.ErrShortRecord) || flat[0].Field != "record" {
+		t.Errorf("Expected short record error, got %v", flat[0])
+	}
+	for i, field := range []string{"status", "expiry", "serial"} {
+		if flat[i+1].Field != field {
+			t.Errorf("Expected error for %s, got %v", field, flat[i+1])
+		}
+	}
+	if inv.Len() != 1 {
+		t.Errorf("Expected only user5 to be read, got %d certificates", inv.Len())
+	}
+}
+
+// TestReadCertDir tests reading issued PEM certificates
+func TestReadCertDir(t *testing.T) {
+	dir := t.TempDir()
+	notAfter := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	writeTestCert(t, filepath.Join(dir, "user1.crt"), "user1", 0x0A, notAfter)
+	writeTestCert(t, filepath.Join(dir, "user9.pem"), "user9", 0x1234, notAfter)
+	os.WriteFile(filepath.Join(dir, "user9.key"), []byte("not a certificate"), 0600)
+	os.WriteFile(filepath.Join(dir, "broken.crt"), []byte("not a certificate"), 0644)
+
+	inv := NewInventory()
+	inv.ParseIndex(strings.NewReader(testIndex))
+	errs := inv.ReadCertDir(dir)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.crt") {
+		t.Errorf("Expected one error for broken.crt, got %v", errs)
+	}
+
+	// The certificate from the directory replaces the index expiry of the same serial
+	cert, _ := inv.Lookup("user1")
+	if cert.Serial != "A" || !cert.NotAfter.Equal(notAfter) {
+		t.Errorf("Expected certificate A until %v for user1, got %+v", notAfter, cert)
+	}
+	cert, ok := inv.Lookup("user9")
+	if !ok || cert.Serial != "1234" {
+		t.Errorf("Expected certificate 1234 for user9, got %+v", cert)
+	}
+
+	if errs := inv.ReadCertDir(filepath.Join(dir, "missing")); len(errs) != 1 {
+		t.Errorf("Expected one error for missing directory, got %v", errs)
+	}
+}
+
+// TestAnnotate tests setting the certificate fields of clients
+func TestAnnotate(t *testing.T) {
+	inv := NewInventory()
+	inv.ParseIndex(strings.NewReader(testIndex))
+
+	client := parser.Client{CommonName: "user2"}
+	inv.Annotate(&client)
+	if client.CertSerial != "B" || !client.CertRevoked || client.CertNotAfter != 1827311445 {
+		t.Errorf("Expected revoked certificate B until 1827311445, got %s %v %d",
+			client.CertSerial, client.CertRevoked, client.CertNotAfter)
+	}
+
+	client = parser.Client{CommonName: "unknown"}
+	inv.Annotate(&client)
+	if client.CertSerial != "" || client.CertNotAfter != 0 {
+		t.Errorf("Expected unknown client to be left unchanged, got %+v", client)
+	}
+}
+
+// TestApplyCRL tests marking certificates revoked by a CRL
+func TestApplyCRL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "crl.pem")
+	nextUpdate := time.Date(2025, 12, 27, 0, 0, 0, 0, time.UTC)
+	writeTestCRL(t, path, []int64{0x0A, 0x99}, nextUpdate)
+
+	crl, err := ReadCRL(path)
+	if err != nil {
+		t.Fatalf("ReadCRL failed: %v", err)
+	}
+	if crl.Len() != 2 || !crl.IsRevoked("A") || crl.IsRevoked("B") {
+		t.Errorf("Expected A and 99 to be revoked, got %d revoked", crl.Len())
+	}
+
+	// user1's current certificate A is in the CRL, user2's revoked
+	// certificate B is not
+	inv := NewInventory()
+	inv.ParseIndex(strings.NewReader(testIndex))
+	info := inv.ApplyCRL(crl, time.Date(2025, 11, 27, 0, 0, 0, 0, time.UTC))
+
+	if info.Revoked != 2 || info.Stale || info.NextUpdate != nextUpdate.Unix() || info.ThisUpdate == 0 {
+		t.Errorf("Expected fresh CRL with 2 revoked until %d, got %+v", nextUpdate.Unix(), info)
+	}
+	if info.MissingRevocations != 1 {
+		t.Errorf("Expected 1 missing revocation, got %d", info.MissingRevocations)
+	}
+
+	client := parser.Client{CommonName: "user1"}
+	inv.Annotate(&client)
+	if !client.CertRevoked || client.CertSerial != "A" {
+		t.Errorf("Expected user1 to be connected with revoked certificate A, got %s %v", client.CertSerial, client.CertRevoked)
+	}
+
+	info = inv.ApplyCRL(crl, nextUpdate.Add(time.Hour))
+	if !info.Stale {
+		t.Error("Expected CRL to be stale after NextUpdate")
+	}
+}
+
+// TestCRLChecker tests flagging clients with a revoked certificate that
+// have a renewed one as well
+func TestCRLChecker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crl.pem")
+	writeTestCRL(t, path, []int64{0x01}, time.Date(2025, 12, 27, 0, 0, 0, 0, time.UTC))
+	crl, err := ReadCRL(path)
+	if err != nil {
+		t.Fatalf("ReadCRL failed: %v", err)
+	}
+
+	// user1's old certificate 01 is in the CRL, Lookup picks the renewed 0A
+	inv := NewInventory()
+	inv.ParseIndex(strings.NewReader(testIndex))
+	checker := NewCRLChecker(inv, crl, time.Date(2024, 11, 27, 0, 0, 0, 0, time.UTC))
+
+	clients := []parser.Client{
+		{CommonName: "user1", RealAddress: "192.168.1.100:54321", ConnectedSinceTime: 1717243200},
+		{CommonName: "user1", RealAddress: "192.168.1.101:54321", ConnectedSinceTime: 1764237600},
+		{CommonName: "user3", RealAddress: "192.168.1.102:54321"},
+	}
+	for i := range clients {
+		inv.Annotate(&clients[i])
+		checker.AnnotateClient(&clients[i])
+	}
+
+	if !clients[0].CertRevoked || clients[0].CertSerial != "1" {
+		t.Errorf("Expected user1 to be flagged with revoked certificate 1, got %s %v",
+			clients[0].CertSerial, clients[0].CertRevoked)
+	}
+	// Certificate 01 had expired when the second session started
+	if clients[1].CertRevoked || clients[1].CertSerial != "A" {
+		t.Errorf("Expected user1 connected in 2025 to use certificate A, got %s %v",
+			clients[1].CertSerial, clients[1].CertRevoked)
+	}
+	if clients[2].CertRevoked {
+		t.Error("Expected user3 not to be flagged")
+	}
+
+	sessions := checker.Sessions()
+	if checker.Info().RevokedConnected != 1 || len(sessions) != 1 {
+		t.Fatalf("Expected 1 revoked session, got %d %v", checker.Info().RevokedConnected, sessions)
+	}
+	if sessions[0] != (RevokedSession{"user1", "192.168.1.100:54321", "1"}) {
+		t.Errorf("Expected user1 session with certificate 1, got %+v", sessions[0])
+	}
+}
+
+// TestReadCRLDir tests a crl-verify directory of revoked serial numbers
+func TestReadCRLDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "10"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "README"), nil, 0644)
+
+	crl, err := ReadCRL(dir)
+	if err != nil {
+		t.Fatalf("ReadCRL failed: %v", err)
+	}
+	if crl.Len() != 1 || !crl.IsRevoked("A") {
+		t.Errorf("Expected only serial 10 (A) to be revoked, got %d revoked", crl.Len())
+	}
+
+	if _, err := ReadCRL(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("Expected error for missing CRL")
+	}
+	if _, err := ReadCRL(filepath.Join(dir, "README")); err == nil {
+		t.Error("Expected error for invalid CRL")
+	}
+}
+
+// writeTestCRL writes a PEM CRL revoking serials, signed by a new CA
+func writeTestCRL(t *testing.T, path string, serials []int64, nextUpdate time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             nextUpdate.AddDate(-1, 0, 0),
+		NotAfter:              nextUpdate.AddDate(1, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		SubjectKeyId:          []byte{1, 2, 3, 4},
+	}
+
+	var entries []x509.RevocationListEntry
+	for _, serial := range serials {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: nextUpdate.AddDate(0, -2, 0),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                nextUpdate.AddDate(0, -1, 0),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, ca, key)
+	if err != nil {
+		t.Fatalf("Failed to create CRL: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write CRL: %v", err)
+	}
+}
+
+// writeTestCert writes a self-signed PEM certificate to path
+func writeTestCert(t *testing.T, path, cn string, serial int64, notAfter time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+}